@@ -0,0 +1,339 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// AStarFinder for recipe search, guided by element tiers
+type AStarFinder struct {
+	store       *ElementStore
+	costModel   CostModel
+	constraints *SearchConstraints
+}
+
+// astarNode is an element waiting in the open set
+type astarNode struct {
+	Element  string
+	Mask     uint64  // Required elements used so far
+	Steps    int     // Combinations made so far
+	Cost     float64 // Cost of the chain so far
+	Estimate int     // Steps plus the estimated remaining steps
+	Order    int     // Insertion order, breaks ties deterministically
+}
+
+// astarQueue is a min-heap on the estimated total steps
+type astarQueue []astarNode
+
+func (q astarQueue) Len() int { return len(q) }
+
+func (q astarQueue) Less(i, j int) bool {
+	if q[i].Estimate != q[j].Estimate {
+		return q[i].Estimate < q[j].Estimate
+	}
+	// Prefer nodes closer to the target
+	if q[i].Steps != q[j].Steps {
+		return q[i].Steps > q[j].Steps
+	}
+	return q[i].Order < q[j].Order
+}
+
+func (q astarQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *astarQueue) Push(x interface{}) { *q = append(*q, x.(astarNode)) }
+
+func (q *astarQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+func init() {
+	RegisterFinder(FinderInfo{
+		Name:        "astar",
+		Label:       "A*",
+		Description: "A* Search (tier heuristic)",
+		Order:       4,
+		New:         func(store *ElementStore) RecipeFinder { return NewAStarFinder(store) },
+	})
+}
+
+// NewAStarFinder creates finder instance
+func NewAStarFinder(store *ElementStore) *AStarFinder {
+	return &AStarFinder{store: store, costModel: StepsCost{}}
+}
+
+// SetCostModel sets the model that picks the cheapest of the paths with the
+// fewest steps
+func (af *AStarFinder) SetCostModel(model CostModel) {
+	af.costModel = model
+}
+
+// SetConstraints sets the elements and recipes the search must avoid or use
+func (af *AStarFinder) SetConstraints(constraints *SearchConstraints) {
+	af.constraints = constraints
+	af.store = af.store.WithConstraints(constraints)
+}
+
+// FindShortestPath finds shortest recipe path using A*
+func (af *AStarFinder) FindShortestPath(target string) (*SearchResult, error) {
+	return af.FindShortestPathContext(context.Background(), target)
+}
+
+// FindShortestPathContext finds shortest recipe path using A*, stopping once ctx is done
+func (af *AStarFinder) FindShortestPathContext(ctx context.Context, target string) (*SearchResult, error) {
+	startTime := time.Now()
+
+	// Check target exists
+	if _, exists := af.store.Elements[target]; !exists {
+		return nil, ErrElementNotFound
+	}
+	targetTier := af.store.GetElementTier(target)
+
+	// Check constraints
+	if err := af.constraints.Validate(af.store); err != nil {
+		return nil, err
+	}
+	required := af.constraints.requirements()
+
+	// Get basic elements
+	basicElements := af.store.GetBasicElements()
+	if len(basicElements) == 0 {
+		return nil, ErrNoBasicElements
+	}
+
+	estimate := af.buildHeuristic(target, targetTier)
+
+	// Count visited nodes
+	visitedCount := 0
+
+	// A* setup
+	open := &astarQueue{}
+	steps := make(map[string]int)
+	costs := make(map[string]float64) // Cost of the cheapest chain with those steps
+	closed := make(map[string]bool)
+	parent := make(map[string]RecipeStep) // Parent tracking
+	order := 0
+
+	// When chains are ranked by cost, a cheaper chain with the same steps
+	// reopens a closed element, and the search goes on after reaching the
+	// target until no open element can reach it in as few steps
+	chains := newChainCost(af.store, af.costModel)
+	ranked := chains.ranks()
+
+	// Initialize open set with basic elements
+	for _, elem := range basicElements {
+		remaining, relevant := estimate(elem.ID)
+		if !relevant {
+			continue
+		}
+		mask := required.add(0, elem.ID)
+		startKey := required.key(searchState{Element: elem.ID, Mask: mask})
+		steps[startKey] = 0
+		costs[startKey] = chains.start(elem.ID)
+		heap.Push(open, astarNode{Element: elem.ID, Mask: mask, Cost: costs[startKey], Estimate: remaining, Order: order})
+		order++
+		visitedCount++
+	}
+
+	found := false
+	goalSteps := 0
+	for open.Len() > 0 {
+		if err := searchStopped(ctx); err != nil {
+			return timedOutResult(visitedCount, startTime), err
+		}
+		if found && (*open)[0].Estimate > goalSteps {
+			break
+		}
+
+		current := heap.Pop(open).(astarNode)
+		currentKey := required.key(searchState{Element: current.Element, Mask: current.Mask})
+		if closed[currentKey] || current.Steps != steps[currentKey] || current.Cost != costs[currentKey] {
+			continue // Stale entry
+		}
+		closed[currentKey] = true
+
+		if current.Element == target && required.complete(current.Mask) {
+			if !found {
+				found = true
+				goalSteps = current.Steps
+			}
+			if !ranked {
+				break
+			}
+			continue
+		}
+
+		for _, recipe := range af.expand(current.Element, targetTier) {
+			resultElem := recipe.Result
+			remaining, relevant := estimate(resultElem)
+			nextMask := required.addRecipe(current.Mask, recipe)
+			nextKey := required.key(searchState{Element: resultElem, Mask: nextMask})
+			if !relevant || (closed[nextKey] && !ranked) {
+				continue
+			}
+
+			nextSteps := current.Steps + 1
+			nextCost := chains.step(current.Cost, current.Element, recipe)
+			if known, seen := steps[nextKey]; seen && (nextSteps > known || (nextSteps == known && nextCost >= costs[nextKey])) {
+				continue
+			} else if !seen {
+				visitedCount++
+			}
+
+			steps[nextKey] = nextSteps
+			costs[nextKey] = nextCost
+			delete(closed, nextKey)
+			parent[nextKey] = RecipeStep{
+				ParentID: currentKey,
+				Recipe:   recipe,
+			}
+			heap.Push(open, astarNode{
+				Element:  resultElem,
+				Mask:     nextMask,
+				Steps:    nextSteps,
+				Cost:     nextCost,
+				Estimate: nextSteps + remaining,
+				Order:    order,
+			})
+			order++
+		}
+	}
+
+	if !found {
+		return nil, af.store.explainNoPath(target, af.constraints, "")
+	}
+
+	// Build path
+	path := af.reconstructPath(required.goalKey(target), parent)
+
+	// Paths are chains, so the other ingredient of a step is not made along the way
+	if err := af.store.VerifyPath(path, target).Err(IssueMissingIngredient); err != nil {
+		return nil, err
+	}
+	tree := BuildRecipeTree(af.store, target, path)
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	return &SearchResult{
+		Path:          path,
+		VisitedNodes:  visitedCount,
+		ExecutionTime: executionTime,
+		TreeStructure: buildTreeStructureFromNode(af.store, tree),
+		Cost:          af.costModel.TreeCost(af.store, tree),
+	}, nil
+}
+
+// expand returns the recipes that use element and climb towards the target
+func (af *AStarFinder) expand(element string, targetTier int) []Recipe {
+	var recipes []Recipe
+	currentTier := af.store.GetElementTier(element)
+
+	for _, recipe := range af.store.Recipes {
+		resultTier := af.store.GetElementTier(recipe.Result)
+		if !af.store.tierBelow(currentTier, resultTier) || !af.store.tierWithin(resultTier, targetTier) {
+			continue
+		}
+
+		for _, ingredient := range recipe.Ingredients {
+			if ingredient == element {
+				recipes = append(recipes, recipe)
+				break
+			}
+		}
+	}
+
+	return recipes
+}
+
+// buildHeuristic returns the A* estimate of the steps left from an element to
+// target. Elements that can never lead to target are reported as irrelevant.
+//
+// The estimate relaxes the graph to tiers: from tier t one step reaches at
+// most the highest result tier of any recipe using a tier t ingredient, so
+// the number of such jumps needed to climb to the target tier never
+// overestimates the real number of steps.
+func (af *AStarFinder) buildHeuristic(target string, targetTier int) func(string) (int, bool) {
+	// Highest tier reachable in one step from each tier
+	maxReach := make(map[int]int)
+	for _, recipe := range af.store.Recipes {
+		resultTier := af.store.GetElementTier(recipe.Result)
+		for _, ingredient := range recipe.Ingredients {
+			ingredientTier := af.store.GetElementTier(ingredient)
+			if resultTier > maxReach[ingredientTier] {
+				maxReach[ingredientTier] = resultTier
+			}
+		}
+	}
+
+	// Fewest jumps from each tier up to the target tier
+	stepsFromTier := make(map[int]int)
+	for tier := targetTier; tier >= 0; tier-- {
+		if tier == targetTier {
+			stepsFromTier[tier] = 0
+			continue
+		}
+
+		best := -1
+		for next := tier + 1; next <= maxReach[tier] && next <= targetTier; next++ {
+			if remaining, ok := stepsFromTier[next]; ok && (best < 0 || remaining+1 < best) {
+				best = remaining + 1
+			}
+		}
+		if best >= 0 {
+			stepsFromTier[tier] = best
+		}
+	}
+
+	// Elements that are an ingredient, directly or not, of the target
+	relevant := map[string]bool{target: true}
+	recipesByResult := af.store.RecipesByResult()
+	queue := []string{target}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, recipe := range recipesByResult[current] {
+			for _, ingredient := range recipe.Ingredients {
+				if !relevant[ingredient] {
+					relevant[ingredient] = true
+					queue = append(queue, ingredient)
+				}
+			}
+		}
+	}
+
+	return func(element string) (int, bool) {
+		if !relevant[element] {
+			return 0, false
+		}
+		// Without tiers there is nothing to estimate from, so A* runs as Dijkstra
+		if af.store.TiersIgnored() {
+			return 0, true
+		}
+		remaining, ok := stepsFromTier[af.store.GetElementTier(element)]
+		return remaining, ok
+	}
+}
+
+// Build path
+func (af *AStarFinder) reconstructPath(target string, parentMap map[string]RecipeStep) []Recipe {
+	var path []Recipe
+	current := target
+
+	// Trace path from target back to a basic element
+	for {
+		step, exists := parentMap[current]
+		if !exists {
+			break // Reached a basic element
+		}
+
+		path = append([]Recipe{step.Recipe}, path...) // Prepend to maintain order
+		current = step.ParentID
+	}
+
+	return path
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAStarMatchesBreadthFirst(t *testing.T) {
+	store := loadTestStore(t)
+	astar := NewAStarFinder(store)
+	bfs := NewBreadthFirstFinder(store)
+
+	// The tier heuristic never overestimates, so A* finds paths as short as
+	// the exhaustive breadth-first search, and fails where it fails
+	for target := range store.Elements {
+		want, wantErr := bfs.FindShortestPath(target)
+		got, err := astar.FindShortestPath(target)
+		if wantErr != nil {
+			if !errors.Is(err, ErrNoPathFound) || !errors.Is(wantErr, ErrNoPathFound) {
+				t.Errorf("FindShortestPath(%q) error = %v, BFS error = %v", target, err, wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("FindShortestPath(%q) error = %v", target, err)
+		}
+		if len(got.Path) != len(want.Path) {
+			t.Errorf("FindShortestPath(%q) has %d steps, BFS has %d", target, len(got.Path), len(want.Path))
+		}
+		if err := store.VerifyPath(got.Path, target).Err(IssueMissingIngredient); err != nil {
+			t.Errorf("FindShortestPath(%q) path is invalid: %v", target, err)
+		}
+	}
+}
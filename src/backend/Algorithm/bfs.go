@@ -2,8 +2,8 @@ package main
 
 import (
     "container/list"
+    "context"
     "fmt"
-    "sync"
     "time"
 )
 
@@ -15,16 +15,71 @@ type RecipeStep struct {
 
 // BreadthFirstFinder for recipe search
 type BreadthFirstFinder struct {
-    store *ElementStore
+    store       *ElementStore
+    costModel   CostModel
+    constraints *SearchConstraints
+    workers     searchWorkers // Share of a worker pool for parallel levels
+    parallel    bool          // Expand each frontier level across the workers
+}
+
+func init() {
+    RegisterFinder(FinderInfo{
+        Name:        "bfs",
+        Label:       "BFS",
+        Description: "Breadth-First Search (BFS)",
+        Order:       1,
+        New:         func(store *ElementStore) RecipeFinder { return NewBreadthFirstFinder(store) },
+    })
+    RegisterFinder(FinderInfo{
+        Name:        "bfs-parallel",
+        Label:       "Parallel BFS",
+        Description: "Parallel Breadth-First Search (level by level)",
+        Order:       5,
+        Sequential:  "bfs",
+        New: func(store *ElementStore) RecipeFinder {
+            finder := NewBreadthFirstFinder(store)
+            finder.SetParallel(true)
+            return finder
+        },
+    })
 }
 
 // NewBreadthFirstFinder creates finder instance
 func NewBreadthFirstFinder(store *ElementStore) *BreadthFirstFinder {
-    return &BreadthFirstFinder{store: store}
+    return &BreadthFirstFinder{store: store, costModel: StepsCost{}}
+}
+
+// SetCostModel sets the model that picks the cheapest of the paths with the
+// fewest steps
+func (bf *BreadthFirstFinder) SetCostModel(model CostModel) {
+    bf.costModel = model
+}
+
+// SetConstraints sets the elements and recipes the search must avoid or use
+func (bf *BreadthFirstFinder) SetConstraints(constraints *SearchConstraints) {
+    bf.constraints = constraints
+    bf.store = bf.store.WithConstraints(constraints)
+}
+
+// SetWorkers sets the pool parallel levels are expanded on, nil for the
+// default, and how many of its workers one search may use, 0 for all of them
+func (bf *BreadthFirstFinder) SetWorkers(pool *WorkerPool, limit int) {
+    bf.workers = searchWorkers{pool: pool, limit: limit}
+}
+
+// SetParallel toggles expanding each frontier level across the search's
+// workers. The path found is the same as with a sequential search.
+func (bf *BreadthFirstFinder) SetParallel(enabled bool) {
+    bf.parallel = enabled
 }
 
 // FindShortestPath finds shortest recipe path
 func (bf *BreadthFirstFinder) FindShortestPath(target string) (*SearchResult, error) {
+    return bf.FindShortestPathContext(context.Background(), target)
+}
+
+// FindShortestPathContext finds shortest recipe path, stopping once ctx is done
+func (bf *BreadthFirstFinder) FindShortestPathContext(ctx context.Context, target string) (*SearchResult, error) {
     startTime := time.Now()
 
     // Check target exists
@@ -34,12 +89,22 @@ func (bf *BreadthFirstFinder) FindShortestPath(target string) (*SearchResult, er
     }
     targetTier := bf.store.GetElementTier(target)
 
+    // Check constraints
+    if err := bf.constraints.Validate(bf.store); err != nil {
+        return nil, err
+    }
+    required := bf.constraints.requirements()
+
     // Get basic elements
     basicElements := bf.store.GetBasicElements()
     if len(basicElements) == 0 {
         return nil, ErrNoBasicElements
     }
 
+    if bf.parallel {
+        return bf.findShortestPathParallel(ctx, target, targetTier, required, basicElements, startTime)
+    }
+
     // Count visited nodes
     visitedCount := 0
 
@@ -47,24 +112,42 @@ func (bf *BreadthFirstFinder) FindShortestPath(target string) (*SearchResult, er
     queue := list.New()
     visited := make(map[string]bool)
     parent := make(map[string]RecipeStep) // Parent tracking
+    level := make(map[string]int)         // Steps from a basic element to each state
+    cost := make(map[string]float64)      // Cost of the chain that reached each state
+    chains := newChainCost(bf.store, bf.costModel)
 
     // Initialize queue with basic elements
     for _, elem := range basicElements {
-        queue.PushBack(elem.ID)
-        visited[elem.ID] = true
+        start := searchState{Element: elem.ID, Mask: required.add(0, elem.ID)}
+        startKey := required.key(start)
+        queue.PushBack(start)
+        visited[startKey] = true
+        cost[startKey] = chains.start(elem.ID)
         visitedCount++
     }
 
-    // Path found flag
+    // Path found flag, and the level the target was reached on. The rest of
+    // the level before it is still expanded, since it may reach the target by
+    // a cheaper chain of the same length.
     found := false
+    goalLevel := 0
 
     // Run BFS
-    for queue.Len() > 0 && !found {
-        current := queue.Remove(queue.Front()).(string)
+    for queue.Len() > 0 {
+        if err := searchStopped(ctx); err != nil {
+            return timedOutResult(visitedCount, startTime), err
+        }
+
+        state := queue.Remove(queue.Front()).(searchState)
+        current := state.Element
+        currentKey := required.key(state)
         currentTier := bf.store.GetElementTier(current)
+        if found && level[currentKey] >= goalLevel {
+            break
+        }
 
         // Check if we found the target
-        if current == target {
+        if current == target && required.complete(state.Mask) {
             found = true
             break
         }
@@ -79,7 +162,7 @@ func (bf *BreadthFirstFinder) FindShortestPath(target string) (*SearchResult, er
             validRecipe := true
             for _, ingredient := range recipe.Ingredients {
                 ingredientTier := bf.store.GetElementTier(ingredient)
-                if ingredientTier >= resultTier {
+                if !bf.store.tierBelow(ingredientTier, resultTier) {
                     validRecipe = false
                     break
                 }
@@ -90,34 +173,59 @@ func (bf *BreadthFirstFinder) FindShortestPath(target string) (*SearchResult, er
             }
             
             // Only consider recipes that lead to higher tiers than current
-            if resultTier <= currentTier {
+            if !bf.store.tierBelow(currentTier, resultTier) {
                 continue
             }
             
-            if !visited[resultElem] {
-                queue.PushBack(resultElem)
-                visited[resultElem] = true
-                parent[resultElem] = RecipeStep{
-                    ParentID: current,
-                    Recipe:   recipe,
+            next := searchState{Element: resultElem, Mask: required.addRecipe(state.Mask, recipe)}
+            nextKey := required.key(next)
+            nextCost := chains.step(cost[currentKey], current, recipe)
+            if visited[nextKey] {
+                // A cheaper chain of the same length replaces the one found first
+                if level[nextKey] == level[currentKey]+1 && nextCost < cost[nextKey] {
+                    parent[nextKey] = RecipeStep{
+                        ParentID: currentKey,
+                        Recipe:   recipe,
+                    }
+                    cost[nextKey] = nextCost
                 }
-                visitedCount++
+                continue
+            }
 
-                // Early exit if we found the target
-                if resultElem == target {
-                    found = true
-                    break
-                }
+            queue.PushBack(next)
+            visited[nextKey] = true
+            level[nextKey] = level[currentKey] + 1
+            cost[nextKey] = nextCost
+            parent[nextKey] = RecipeStep{
+                ParentID: currentKey,
+                Recipe:   recipe,
+            }
+            visitedCount++
+
+            // Finish this level once the target is found
+            if resultElem == target && required.complete(next.Mask) && !found {
+                found = true
+                goalLevel = level[nextKey]
             }
         }
     }
 
     if !found {
-        return nil, ErrNoPathFound
+        return nil, bf.store.explainNoPath(target, bf.constraints, "")
     }
 
     // Build path
-    path := bf.reconstructPath(target, parent)
+    path := bf.reconstructPath(required.goalKey(target), parent)
+
+    return bf.pathResult(path, target, visitedCount, startTime)
+}
+
+// pathResult verifies the path a search found to target and builds its result
+func (bf *BreadthFirstFinder) pathResult(path []Recipe, target string, visitedCount int, startTime time.Time) (*SearchResult, error) {
+    // Paths are chains, so the other ingredient of a step is not made along the way
+    if err := bf.store.VerifyPath(path, target).Err(IssueMissingIngredient); err != nil {
+        return nil, err
+    }
 
     // Visualize tree
     treeStructure := bf.buildTreeStructure(path, target)
@@ -129,110 +237,139 @@ func (bf *BreadthFirstFinder) FindShortestPath(target string) (*SearchResult, er
         VisitedNodes:  visitedCount,
         ExecutionTime: executionTime,
         TreeStructure: treeStructure,
+        Cost:          bf.costModel.TreeCost(bf.store, BuildRecipeTree(bf.store, target, path)),
     }, nil
 }
 
-// FindMultiplePaths finds multiple recipe paths using multithreading
-func (bf *BreadthFirstFinder) FindMultiplePaths(target string, maxPaths int) ([]*SearchResult, error) {
-    // Check target exists
-    _, exists := bf.store.Elements[target]  // Remove targetElem
-    if !exists {
-        return nil, ErrElementNotFound
-    }
-    targetTier := bf.store.GetElementTier(target)
+// FindFromElement finds the shortest chain of combinations that leads from
+// start, an element the player already has, to target. Every step uses the
+// element made by the step before, and the other ingredients are made from the
+// basic elements and start, so the path is a complete derivation.
+func (bf *BreadthFirstFinder) FindFromElement(start, target string) (*SearchResult, error) {
+    return bf.FindFromElementContext(context.Background(), start, target)
+}
 
-    var results []*SearchResult
-    var mutex sync.Mutex
-    var wg sync.WaitGroup
+// FindFromElementContext finds the shortest derivation from start, stopping once ctx is done
+func (bf *BreadthFirstFinder) FindFromElementContext(ctx context.Context, start, target string) (*SearchResult, error) {
+    startTime := time.Now()
 
-    // Limit goroutines based on system capabilities
-    maxGoroutines := 4 // Adjust based on system
-    if maxGoroutines > maxPaths {
-        maxGoroutines = maxPaths
+    // Check both elements exist
+    for _, element := range []string{start, target} {
+        if _, exists := bf.store.Elements[element]; !exists {
+            return nil, fmt.Errorf("element %q: %w", element, ErrElementNotFound)
+        }
     }
+    targetTier := bf.store.GetElementTier(target)
 
-    // Concurrency control
-    sem := make(chan bool, maxGoroutines)
-    
-    // First, find the shortest path
-    shortestPath, err := bf.FindShortestPath(target)
-    if err != nil {
+    // Check constraints
+    if err := bf.constraints.Validate(bf.store); err != nil {
         return nil, err
     }
-    
-    // Add the shortest path to results
-    mutex.Lock()
-    results = append(results, shortestPath)
-    mutex.Unlock()
-    
-    // Track paths we've already found to avoid duplicates
-    pathSignatures := make(map[string]bool)
-    pathSignatureMutex := sync.Mutex{}
-    
-    // Add signature of first path
-    if len(shortestPath.Path) > 0 {
-        sig := getPathSignature(shortestPath.Path)
-        pathSignatureMutex.Lock()
-        pathSignatures[sig] = true
-        pathSignatureMutex.Unlock()
+    required := bf.constraints.requirements()
+
+    if len(bf.store.BasicElements) == 0 {
+        return nil, ErrNoBasicElements
     }
 
-    // Start multiple searches with different variations
-    for i := 0; i < maxPaths-1; i++ {
-        wg.Add(1)
-        sem <- true
+    // The other ingredients of each step come from the basics and start
+    leaves := append([]string{start}, bf.store.BasicElements...)
+    costs := NewRecipeTreeFinder(bf.store).solveFrom(leaves)
 
-        go func(index int) {
-            defer wg.Done()
-            defer func() { <-sem }()
+    // BFS setup, anchored at start
+    queue := list.New()
+    visited := make(map[string]bool)
+    parent := make(map[string]RecipeStep)
 
-            // Find variation path with constraints on tier and ingredients
-            result, err := bf.findPathVariation(target, index, targetTier, pathSignatures, &pathSignatureMutex)
+    first := searchState{Element: start, Mask: required.add(0, start)}
+    queue.PushBack(first)
+    visited[required.key(first)] = true
+    visitedCount := 1
 
-            if err == nil && result != nil {
-                mutex.Lock()
-                results = append(results, result)
-                mutex.Unlock()
-                
-                // Add path signature to avoid duplicates
-                sig := getPathSignature(result.Path)
-                pathSignatureMutex.Lock()
-                pathSignatures[sig] = true
-                pathSignatureMutex.Unlock()
+    found := start == target && required.complete(first.Mask)
+    for queue.Len() > 0 && !found {
+        if err := searchStopped(ctx); err != nil {
+            return timedOutResult(visitedCount, startTime), err
+        }
+
+        state := queue.Remove(queue.Front()).(searchState)
+        currentKey := required.key(state)
+        currentTier := bf.store.GetElementTier(state.Element)
+
+        for _, recipe := range bf.getPossibleRecipesThatRespectTiers(state.Element, currentTier, targetTier) {
+            // The other ingredient must be makeable without this chain
+            makeable := true
+            for _, ingredient := range recipe.Ingredients {
+                if _, ok := costs[ingredient]; !ok && ingredient != state.Element {
+                    makeable = false
+                    break
+                }
+            }
+            if !makeable {
+                continue
             }
-        }(i)
-    }
 
-    wg.Wait()
+            next := searchState{Element: recipe.Result, Mask: required.addRecipe(state.Mask, recipe)}
+            nextKey := required.key(next)
+            if visited[nextKey] {
+                continue
+            }
+            queue.PushBack(next)
+            visited[nextKey] = true
+            parent[nextKey] = RecipeStep{
+                ParentID: currentKey,
+                Recipe:   recipe,
+            }
+            visitedCount++
 
-    // Check results
-    if len(results) == 0 {
-        return nil, ErrNoPathFound
+            if recipe.Result == target && required.complete(next.Mask) {
+                found = true
+                break
+            }
+        }
     }
 
-    return results, nil
-}
+    if !found {
+        return nil, bf.store.explainNoPath(target, bf.constraints, "")
+    }
 
-// Get a unique signature for a path to detect duplicates
-func getPathSignature(path []Recipe) string {
-    if len(path) == 0 {
-        return ""
+    // Make the other ingredients of each step before the step itself
+    chosen := make(map[string]Recipe)
+    for element, choice := range costs {
+        if !choice.Basic {
+            chosen[element] = choice.Recipe
+        }
     }
-    
-    // Simple signature based on sequence of results
-    sig := ""
-    for _, recipe := range path {
-        sig += recipe.Result + ":"
-        // Sort ingredients to normalize the recipe
-        if len(recipe.Ingredients) >= 2 {
-            if recipe.Ingredients[0] > recipe.Ingredients[1] {
-                sig += recipe.Ingredients[1] + "+" + recipe.Ingredients[0] + ";"
-            } else {
-                sig += recipe.Ingredients[0] + "+" + recipe.Ingredients[1] + ";"
-            }
+    available := make(map[string]bool)
+    for _, leaf := range leaves {
+        available[leaf] = true
+    }
+
+    var path []Recipe
+    for _, step := range bf.reconstructPath(required.goalKey(target), parent) {
+        for _, recipe := range planOrder(step.Ingredients, chosen, available) {
+            path = append(path, recipe)
+            available[recipe.Result] = true
         }
+        path = append(path, step)
+        available[step.Result] = true
+    }
+    if err := bf.store.VerifyPath(path, target, start).Err(); err != nil {
+        return nil, err
     }
-    return sig
+
+    tree := BuildRecipeTree(bf.store, target, path)
+    markAvailableLeaves(tree, map[string]bool{start: true})
+
+    executionTime := time.Since(startTime).Milliseconds()
+
+    return &SearchResult{
+        Path:          path,
+        VisitedNodes:  visitedCount,
+        ExecutionTime: executionTime,
+        TreeStructure: buildTreeStructureFromNode(bf.store, tree),
+        Tree:          tree,
+        Cost:          bf.costModel.TreeCost(bf.store, tree),
+    }, nil
 }
 
 // Get recipes using element that respect tier hierarchy
@@ -258,20 +395,20 @@ func (bf *BreadthFirstFinder) getPossibleRecipesThatRespectTiers(elementID strin
         resultTier := bf.store.GetElementTier(recipe.Result)
         
         // Skip if result tier is higher than target tier (avoid going beyond what we need)
-        if resultTier > targetTier {
+        if !bf.store.tierWithin(resultTier, targetTier) {
             continue
         }
         
         allIngredientsLowerTier := true
         for _, ingredient := range recipe.Ingredients {
             ingredientTier := bf.store.GetElementTier(ingredient)
-            if ingredientTier >= resultTier {
+            if !bf.store.tierBelow(ingredientTier, resultTier) {
                 allIngredientsLowerTier = false
                 break
             }
         }
         
-        if allIngredientsLowerTier && resultTier > currentTier {
+        if allIngredientsLowerTier && bf.store.tierBelow(currentTier, resultTier) {
             recipes = append(recipes, recipe)
         }
     }
@@ -298,142 +435,6 @@ func (bf *BreadthFirstFinder) reconstructPath(target string, parentMap map[strin
     return path
 }
 
-// Find a path variation using BFS with constraints
-func (bf *BreadthFirstFinder) findPathVariation(
-    target string, 
-    variationIndex int, 
-    targetTier int,
-    existingPaths map[string]bool, 
-    pathMutex *sync.Mutex) (*SearchResult, error) {
-    
-    startTime := time.Now()
-
-    // Get basic elements
-    basicElements := bf.store.GetBasicElements()
-    if len(basicElements) == 0 {
-        return nil, ErrNoBasicElements
-    }
-
-    // Count visited nodes
-    visitedCount := 0
-
-    // BFS setup
-    queue := list.New()
-    visited := make(map[string]bool)
-    parent := make(map[string]RecipeStep) // Parent tracking
-
-    // Initialize queue with basic elements, but in a different order based on variationIndex
-    // This helps us find different paths
-    offset := variationIndex % len(basicElements)
-    for i := 0; i < len(basicElements); i++ {
-        elemIndex := (i + offset) % len(basicElements)
-        elem := basicElements[elemIndex]
-        queue.PushBack(elem.ID)
-        visited[elem.ID] = true
-        visitedCount++
-    }
-
-    // Path found flag
-    found := false
-
-    // Add some "randomness" in recipe selection based on variationIndex
-    randomFactor := variationIndex % 10
-    
-    // Run BFS with some variations
-    for queue.Len() > 0 && !found {
-        current := queue.Remove(queue.Front()).(string)
-        currentTier := bf.store.GetElementTier(current)
-
-        // Check if we found the target
-        if current == target {
-            found = true
-            break
-        }
-
-        // Get possible recipes
-        possibleRecipes := bf.getPossibleRecipesThatRespectTiers(current, currentTier, targetTier)
-        
-        // Reorder recipes based on variationIndex to encourage finding different paths
-        if len(possibleRecipes) > 1 && randomFactor > 0 {
-            // Simple reordering: swap elements based on variationIndex
-            for i := 0; i < len(possibleRecipes)-1; i += 2 {
-                if i+1 < len(possibleRecipes) && (i+randomFactor)%7 == 0 {
-                    possibleRecipes[i], possibleRecipes[i+1] = possibleRecipes[i+1], possibleRecipes[i]
-                }
-            }
-        }
-        
-        for _, recipe := range possibleRecipes {
-            resultElem := recipe.Result
-            resultTier := bf.store.GetElementTier(resultElem)
-            
-            // Skip if any ingredient in the recipe doesn't respect tier constraint
-            validRecipe := true
-            for _, ingredient := range recipe.Ingredients {
-                ingredientTier := bf.store.GetElementTier(ingredient)
-                if ingredientTier >= resultTier {
-                    validRecipe = false
-                    break
-                }
-            }
-            
-            if !validRecipe {
-                continue
-            }
-            
-            if !visited[resultElem] {
-                queue.PushBack(resultElem)
-                visited[resultElem] = true
-                parent[resultElem] = RecipeStep{
-                    ParentID: current,
-                    Recipe:   recipe,
-                }
-                visitedCount++
-
-                // Early exit if we found the target
-                if resultElem == target {
-                    found = true
-                    
-                    // Check if this path is unique compared to existing ones
-                    path := bf.reconstructPath(target, parent)
-                    pathSignature := getPathSignature(path)
-                    
-                    pathMutex.Lock()
-                    pathExists := existingPaths[pathSignature]
-                    pathMutex.Unlock()
-                    
-                    if pathExists {
-                        // This path is a duplicate, continue searching
-                        found = false
-                    }
-                    
-                    break
-                }
-            }
-        }
-    }
-
-    if !found {
-        return nil, ErrNoPathFound
-    }
-
-    // Build path
-    path := bf.reconstructPath(target, parent)
-
-    // Visualize tree
-    treeStructure := bf.buildTreeStructure(path, target)
-
-    executionTime := time.Since(startTime).Milliseconds()
-
-    return &SearchResult{
-        Path:          path,
-        VisitedNodes:  visitedCount,
-        ExecutionTime: executionTime,
-        TreeStructure: treeStructure,
-        VariationIndex: variationIndex,
-    }, nil
-}
-
 // Create visualization tree
 func (bf *BreadthFirstFinder) buildTreeStructure(path []Recipe, target string) interface{} {
     // Build tree for Next.js visualization - target as root, basics as leaves
@@ -450,13 +451,13 @@ func (bf *BreadthFirstFinder) buildTreeStructure(path []Recipe, target string) i
     // Add target node
     targetElem := bf.store.Elements[target]
     targetTier := bf.store.GetElementTier(target)
-    nodes = append(nodes, map[string]interface{}{
+    nodes = append(nodes, bf.store.annotateUnlock(map[string]interface{}{
         "id":       target,
         "label":    target,
         "type":     "target",
         "tier":     targetTier,
         "imageUrl": targetElem.ImageURL,
-    })
+    }, target))
     nodeMap[target] = true
     
     // Helper function to recursively build the tree from target to basics
@@ -468,6 +469,9 @@ func (bf *BreadthFirstFinder) buildTreeStructure(path []Recipe, target string) i
             return
         }
         
+        // Expand each element once, without tiers an ingredient can lead back to it
+        delete(recipesByResult, element)
+        
         // Add ingredients as nodes and connect them with edges
         for _, ingredient := range recipe.Ingredients {
             if !nodeMap[ingredient] {
@@ -479,13 +483,13 @@ func (bf *BreadthFirstFinder) buildTreeStructure(path []Recipe, target string) i
                     ingType = "basic" // Mark basic elements
                 }
                 
-                nodes = append(nodes, map[string]interface{}{
+                nodes = append(nodes, bf.store.annotateUnlock(map[string]interface{}{
                     "id":       ingredient,
                     "label":    ingredient,
                     "type":     ingType,
                     "tier":     ingTier,
                     "imageUrl": ingElem.ImageURL,
-                })
+                }, ingredient))
                 nodeMap[ingredient] = true
             }
             
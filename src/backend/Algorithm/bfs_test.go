@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindFromElement(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  string
+		target string
+		want   int // Combinations in the derivation
+		err    error
+	}{
+		{name: "start already made", start: "Mud", target: "Mud", want: 0},
+		{name: "chain through start", start: "Mud", target: "Wall", want: 3},
+		{name: "start the basics cannot make", start: "Ghost", target: "Golem", want: 3},
+		{name: "target below start", start: "Wall", target: "Mud", err: ErrNoPathFound},
+		{name: "target not led to by start", start: "Ghost", target: "Wall", err: ErrNoPathFound},
+		{name: "unknown start", start: "Gold", target: "Wall", err: ErrElementNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+
+			result, err := NewBreadthFirstFinder(store).FindFromElement(tt.start, tt.target)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("FindFromElement(%q, %q) error = %v, want %v", tt.start, tt.target, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindFromElement(%q, %q) error = %v", tt.start, tt.target, err)
+			}
+			if len(result.Path) != tt.want {
+				t.Errorf("FindFromElement(%q, %q) made %d combinations, want %d: %v", tt.start, tt.target, len(result.Path), tt.want, result.Path)
+			}
+
+			// The derivation is complete given start, and start is used
+			// rather than made again
+			if err := store.VerifyPath(result.Path, tt.target, tt.start).Err(); err != nil {
+				t.Errorf("derivation is invalid: %v", err)
+			}
+			used := tt.start == tt.target
+			for _, recipe := range result.Path {
+				if recipe.Result == tt.start {
+					t.Errorf("derivation makes %s again: %v", tt.start, result.Path)
+				}
+				if usesIngredient(recipe, tt.start) {
+					used = true
+				}
+			}
+			if !used {
+				t.Errorf("derivation never uses %s: %v", tt.start, result.Path)
+			}
+		})
+	}
+}
@@ -2,23 +2,52 @@ package main
 
 import (
     "container/list"
+    "context"
     "fmt"
-    "sync"
     "time"
 )
 
 // BidirectionalFinder for recipe search
 type BidirectionalFinder struct {
-    store *ElementStore
+    store       *ElementStore
+    costModel   CostModel
+    constraints *SearchConstraints
+}
+
+func init() {
+    RegisterFinder(FinderInfo{
+        Name:        "bidirectional",
+        Label:       "Bidirectional",
+        Description: "Bidirectional Search",
+        Order:       3,
+        New:         func(store *ElementStore) RecipeFinder { return NewBidirectionalFinder(store) },
+    })
 }
 
 // NewBidirectionalFinder creates finder instance
 func NewBidirectionalFinder(store *ElementStore) *BidirectionalFinder {
-    return &BidirectionalFinder{store: store}
+    return &BidirectionalFinder{store: store, costModel: StepsCost{}}
+}
+
+// SetCostModel sets the model that picks the cheapest of the shortest paths
+// through the points where the two searches first meet
+func (bf *BidirectionalFinder) SetCostModel(model CostModel) {
+    bf.costModel = model
+}
+
+// SetConstraints sets the elements and recipes the search must avoid or use
+func (bf *BidirectionalFinder) SetConstraints(constraints *SearchConstraints) {
+    bf.constraints = constraints
+    bf.store = bf.store.WithConstraints(constraints)
 }
 
 // FindShortestPath finds shortest recipe path
 func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, error) {
+    return bf.FindShortestPathContext(context.Background(), target)
+}
+
+// FindShortestPathContext finds shortest recipe path, stopping once ctx is done
+func (bf *BidirectionalFinder) FindShortestPathContext(ctx context.Context, target string) (*SearchResult, error) {
     startTime := time.Now()
 
     // Check target exists
@@ -28,6 +57,12 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
     }
     targetTier := bf.store.GetElementTier(target)
 
+    // Check constraints
+    if err := bf.constraints.Validate(bf.store); err != nil {
+        return nil, err
+    }
+    required := bf.constraints.requirements()
+
     // Get basic elements
     basicElements := bf.store.GetBasicElements()
     if len(basicElements) == 0 {
@@ -42,12 +77,20 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
     forwardVisited := make(map[string]bool)
     forwardParent := make(map[string]RecipeStep) // Parent tracking
     forwardTier := make(map[string]int)         // Track tier for each element in forward search
+    forwardMasks := make(map[string][]uint64)   // Required elements used on each forward visit
+    forwardLevel := make(map[string]int)        // Steps from a basic element to each forward state
+    forwardCost := make(map[string]float64)     // Cost of the chain that reached each forward state
+    chains := newChainCost(bf.store, bf.costModel)
 
     // Init forward queue
     for _, elem := range basicElements {
-        forwardQueue.PushBack(elem.ID)
-        forwardVisited[elem.ID] = true
+        start := searchState{Element: elem.ID, Mask: required.add(0, elem.ID)}
+        startKey := required.key(start)
+        forwardQueue.PushBack(start)
+        forwardVisited[startKey] = true
+        forwardCost[startKey] = chains.start(elem.ID)
         forwardTier[elem.ID] = 0 // Basic elements are tier 0
+        forwardMasks[elem.ID] = append(forwardMasks[elem.ID], start.Mask)
         visitedCount++
     }
 
@@ -56,30 +99,42 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
     backwardVisited := make(map[string]bool)
     backwardParent := make(map[string]RecipeStep) // Child tracking
     backwardTier := make(map[string]int)         // Track tier for each element in backward search
+    backwardMasks := make(map[string][]uint64)   // Required elements used on each backward visit
 
     // Init backward queue
-    backwardQueue.PushBack(target)
-    backwardVisited[target] = true
+    goal := searchState{Element: target, Mask: required.add(0, target)}
+    backwardQueue.PushBack(goal)
+    backwardVisited[required.key(goal)] = true
     backwardTier[target] = targetTier
+    backwardMasks[target] = append(backwardMasks[target], goal.Mask)
     visitedCount++
 
-    // Meeting point
-    var meetingPoint string
+    // Meeting points, each reached by the two searches with their own required
+    // elements. When chains are ranked by cost, the rest of the half-level
+    // the searches first meet in is searched for more of them.
+    var meetings [][2]string
     found := false
+    ranked := chains.ranks()
 
     // Run bidirectional BFS with tier constraints
     for forwardQueue.Len() > 0 && backwardQueue.Len() > 0 && !found {
         // Forward search step
         levelSize := forwardQueue.Len()
-        for i := 0; i < levelSize && !found; i++ {
-            current := forwardQueue.Remove(forwardQueue.Front()).(string)
+        for i := 0; i < levelSize && (!found || ranked); i++ {
+            if err := searchStopped(ctx); err != nil {
+                return timedOutResult(visitedCount, startTime), err
+            }
+
+            state := forwardQueue.Remove(forwardQueue.Front()).(searchState)
+            current := state.Element
+            currentKey := required.key(state)
             currentTier := forwardTier[current]
             
             // Check meeting point
-            if backwardVisited[current] {
-                meetingPoint = current
+            if other, met := bf.meetingMask(required, state.Mask, backwardMasks[current]); met {
+                meetings = append(meetings, [2]string{currentKey, required.key(searchState{Element: current, Mask: other})})
                 found = true
-                break
+                continue
             }
 
             // Expand forward - respect tier hierarchy
@@ -92,7 +147,7 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
                 allIngredientsLowerTier := true
                 for _, ingredient := range recipe.Ingredients {
                     ingredientTier := bf.store.GetElementTier(ingredient)
-                    if ingredientTier >= resultTier {
+                    if !bf.store.tierBelow(ingredientTier, resultTier) {
                         allIngredientsLowerTier = false
                         break
                     }
@@ -104,25 +159,42 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
                 }
                 
                 // Only consider recipes that lead to higher tiers
-                if resultTier <= currentTier {
+                if !bf.store.tierBelow(currentTier, resultTier) {
                     continue
                 }
                 
-                if !forwardVisited[resultElem] {
-                    forwardQueue.PushBack(resultElem)
-                    forwardVisited[resultElem] = true
+                next := searchState{Element: resultElem, Mask: required.addRecipe(state.Mask, recipe)}
+                nextKey := required.key(next)
+                nextCost := chains.step(forwardCost[currentKey], current, recipe)
+                if forwardVisited[nextKey] {
+                    // A cheaper chain of the same length replaces the one found first
+                    if forwardLevel[nextKey] == forwardLevel[currentKey]+1 && nextCost < forwardCost[nextKey] {
+                        forwardParent[nextKey] = RecipeStep{
+                            ParentID: currentKey,
+                            Recipe:   recipe,
+                        }
+                        forwardCost[nextKey] = nextCost
+                    }
+                } else {
+                    forwardQueue.PushBack(next)
+                    forwardVisited[nextKey] = true
+                    forwardLevel[nextKey] = forwardLevel[currentKey] + 1
+                    forwardCost[nextKey] = nextCost
                     forwardTier[resultElem] = resultTier
-                    forwardParent[resultElem] = RecipeStep{
-                        ParentID: current,
+                    forwardMasks[resultElem] = append(forwardMasks[resultElem], next.Mask)
+                    forwardParent[nextKey] = RecipeStep{
+                        ParentID: currentKey,
                         Recipe:   recipe,
                     }
                     visitedCount++
                     
                     // Check if we've met the backward search
-                    if backwardVisited[resultElem] {
-                        meetingPoint = resultElem
+                    if other, met := bf.meetingMask(required, next.Mask, backwardMasks[resultElem]); met {
+                        meetings = append(meetings, [2]string{nextKey, required.key(searchState{Element: resultElem, Mask: other})})
                         found = true
-                        break
+                        if !ranked {
+                            break
+                        }
                     }
                 }
             }
@@ -134,15 +206,21 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
 
         // Backward search step
         levelSize = backwardQueue.Len()
-        for i := 0; i < levelSize && !found; i++ {
-            current := backwardQueue.Remove(backwardQueue.Front()).(string)
+        for i := 0; i < levelSize && (!found || ranked); i++ {
+            if err := searchStopped(ctx); err != nil {
+                return timedOutResult(visitedCount, startTime), err
+            }
+
+            state := backwardQueue.Remove(backwardQueue.Front()).(searchState)
+            current := state.Element
+            currentKey := required.key(state)
             currentTier := backwardTier[current]
             
             // Check meeting point
-            if forwardVisited[current] {
-                meetingPoint = current
+            if other, met := bf.meetingMask(required, state.Mask, forwardMasks[current]); met {
+                meetings = append(meetings, [2]string{required.key(searchState{Element: current, Mask: other}), currentKey})
                 found = true
-                break
+                continue
             }
 
             // Expand backward - but respect tier hierarchy
@@ -153,7 +231,7 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
                     allIngredientsLowerTier := true
                     for _, ingredient := range recipe.Ingredients {
                         ingredientTier := bf.store.GetElementTier(ingredient)
-                        if ingredientTier >= currentTier {
+                        if !bf.store.tierBelow(ingredientTier, currentTier) {
                             allIngredientsLowerTier = false
                             break
                         }
@@ -169,30 +247,35 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
                         ingredientTier := bf.store.GetElementTier(ingredient)
                         
                         // Only consider ingredients from lower tiers
-                        if ingredientTier >= currentTier {
+                        if !bf.store.tierBelow(ingredientTier, currentTier) {
                             continue
                         }
                         
-                        if !backwardVisited[ingredient] {
-                            backwardQueue.PushBack(ingredient)
-                            backwardVisited[ingredient] = true
+                        next := searchState{Element: ingredient, Mask: required.addRecipe(state.Mask, recipe)}
+                        nextKey := required.key(next)
+                        if !backwardVisited[nextKey] {
+                            backwardQueue.PushBack(next)
+                            backwardVisited[nextKey] = true
                             backwardTier[ingredient] = ingredientTier
-                            backwardParent[ingredient] = RecipeStep{
-                                ParentID: current, 
+                            backwardMasks[ingredient] = append(backwardMasks[ingredient], next.Mask)
+                            backwardParent[nextKey] = RecipeStep{
+                                ParentID: currentKey, 
                                 Recipe:   recipe,
                             }
                             visitedCount++
                             
                             // Check if we've met the forward search
-                            if forwardVisited[ingredient] {
-                                meetingPoint = ingredient
+                            if other, met := bf.meetingMask(required, next.Mask, forwardMasks[ingredient]); met {
+                                meetings = append(meetings, [2]string{required.key(searchState{Element: ingredient, Mask: other}), nextKey})
                                 found = true
-                                break
+                                if !ranked {
+                                    break
+                                }
                             }
                         }
                     }
                     
-                    if found {
+                    if found && !ranked {
                         break
                     }
                 }
@@ -201,17 +284,29 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
     }
 
     if !found {
-        return nil, ErrNoPathFound
+        return nil, bf.store.explainNoPath(target, bf.constraints, "")
     }
 
-    // Build path
-    forwardPath := bf.reconstructForwardPath(meetingPoint, forwardParent)
-    backwardPath := bf.reconstructBackwardPath(meetingPoint, backwardParent)
-
-    // Combine paths
+    // Build the path through every meeting point, keeping the shortest and
+    // then the cheapest, the first found on ties
     var completePath []Recipe
-    completePath = append(completePath, forwardPath...)
-    completePath = append(completePath, backwardPath...)
+    bestCost := 0.0
+    for i, meeting := range meetings {
+        var path []Recipe
+        path = append(path, bf.reconstructForwardPath(meeting[0], forwardParent)...)
+        path = append(path, bf.reconstructBackwardPath(meeting[1], backwardParent)...)
+
+        cost := chains.path(path)
+        if i == 0 || len(path) < len(completePath) || (len(path) == len(completePath) && cost < bestCost) {
+            completePath = path
+            bestCost = cost
+        }
+    }
+
+    // Paths are chains, so the other ingredient of a step is not made along the way
+    if err := bf.store.VerifyPath(completePath, target).Err(IssueMissingIngredient); err != nil {
+        return nil, err
+    }
 
     // Visualize tree
     treeStructure := bf.buildTreeStructure(completePath, target)
@@ -223,132 +318,19 @@ func (bf *BidirectionalFinder) FindShortestPath(target string) (*SearchResult, e
         VisitedNodes:  visitedCount,
         ExecutionTime: executionTime,
         TreeStructure: treeStructure,
+        Cost:          bf.costModel.TreeCost(bf.store, BuildRecipeTree(bf.store, target, completePath)),
     }, nil
 }
 
-// FindMultiplePaths finds multiple recipe paths using multithreading
-func (bf *BidirectionalFinder) FindMultiplePaths(target string, maxPaths int) ([]*SearchResult, error) {
-    // Check target exists
-    _, exists := bf.store.Elements[target]
-    if !exists {
-        return nil, ErrElementNotFound
-    }
-
-    var results []*SearchResult
-    var mutex sync.Mutex
-    var wg sync.WaitGroup
-
-    // Limit goroutines based on available CPUs
-    maxGoroutines := 4 // Can be adjusted based on system cores
-    if maxGoroutines > maxPaths {
-        maxGoroutines = maxPaths
-    }
-
-    // Channel for concurrency control
-    sem := make(chan bool, maxGoroutines)
-    
-    // Channel to collect found paths
-    foundPaths := make(chan *SearchResult, maxPaths)
-    
-    // Stop signal for goroutines when we've found enough paths
-    done := make(chan bool)
-    pathsFound := 0
-    
-    // Start multiple searches with different variations
-    for i := 0; i < maxPaths*2; i++ { // Try more variations than needed
-        wg.Add(1)
-        sem <- true
-        
-        go func(index int) {
-            defer wg.Done()
-            defer func() { <-sem }()
-            
-            // Create variation of finder parameters based on index
-            select {
-            case <-done:
-                return // Stop if we've found enough paths
-            default:
-                // Find variation path with different criteria
-                result, err := bf.findPathWithVariation(target, index)
-                
-                if err == nil && result != nil {
-                    // Verify the path is valid (all ingredients have lower tier than results)
-                    isValid := true
-                    for _, recipe := range result.Path {
-                        resultTier := bf.store.GetElementTier(recipe.Result)
-                        
-                        for _, ingredient := range recipe.Ingredients {
-                            ingredientTier := bf.store.GetElementTier(ingredient)
-                            if ingredientTier >= resultTier {
-                                isValid = false
-                                break
-                            }
-                        }
-                        
-                        if !isValid {
-                            break
-                        }
-                    }
-                    
-                    if isValid {
-                        // Check if we already have this path (deduplicate)
-                        isDuplicate := false
-                        
-                        mutex.Lock()
-                        for _, existingResult := range results {
-                            if len(existingResult.Path) == len(result.Path) {
-                                // Simple equality check - could be more sophisticated
-                                sameSteps := true
-                                for i, recipe := range existingResult.Path {
-                                    if recipe.Result != result.Path[i].Result {
-                                        sameSteps = false
-                                        break
-                                    }
-                                }
-                                
-                                if sameSteps {
-                                    isDuplicate = true
-                                    break
-                                }
-                            }
-                        }
-                        mutex.Unlock()
-                        
-                        if !isDuplicate {
-                            foundPaths <- result
-                        }
-                    }
-                }
-            }
-        }(i)
-    }
-    
-    // Collect results in a separate goroutine
-    go func() {
-        for result := range foundPaths {
-            mutex.Lock()
-            if pathsFound < maxPaths {
-                results = append(results, result)
-                pathsFound++
-                
-                if pathsFound >= maxPaths {
-                    close(done) // Signal other goroutines to stop
-                }
-            }
-            mutex.Unlock()
+// meetingMask finds a mask seen by the other search that, together with mask,
+// uses every required element
+func (bf *BidirectionalFinder) meetingMask(required *requirements, mask uint64, others []uint64) (uint64, bool) {
+    for _, other := range others {
+        if required.complete(mask | other) {
+            return other, true
         }
-    }()
-    
-    // Wait for all searches to complete
-    wg.Wait()
-    close(foundPaths)
-    
-    // Check results
-    if len(results) == 0 {
-        return nil, ErrNoPathFound
     }
-    
-    return results, nil
+    return 0, false
 }
 
 // Get valid recipes that contain the given element in any position
@@ -374,7 +356,7 @@ func (bf *BidirectionalFinder) getValidRecipesWithElementAnyPosition(elementID s
             
             for _, ingredient := range recipe.Ingredients {
                 ingredientTier := bf.store.GetElementTier(ingredient)
-                if ingredientTier >= resultTier {
+                if !bf.store.tierBelow(ingredientTier, resultTier) {
                     allIngredientsLowerTier = false
                     break
                 }
@@ -400,22 +382,6 @@ func (bf *BidirectionalFinder) reconstructForwardPath(meetingPoint string, paren
             break // Reached basic element
         }
         
-        // Verify tier constraints for the recipe
-        resultTier := bf.store.GetElementTier(step.Recipe.Result)
-        allIngredientsLowerTier := true
-        
-        for _, ingredient := range step.Recipe.Ingredients {
-            ingredientTier := bf.store.GetElementTier(ingredient)
-            if ingredientTier >= resultTier {
-                allIngredientsLowerTier = false
-                break
-            }
-        }
-        
-        if !allIngredientsLowerTier {
-            break // Skip invalid recipe
-        }
-        
         path = append([]Recipe{step.Recipe}, path...) // Prepend
         current = step.ParentID
     }
@@ -434,22 +400,6 @@ func (bf *BidirectionalFinder) reconstructBackwardPath(meetingPoint string, chil
             break
         }
         
-        // Verify tier constraints for the recipe
-        resultTier := bf.store.GetElementTier(step.Recipe.Result)
-        allIngredientsLowerTier := true
-        
-        for _, ingredient := range step.Recipe.Ingredients {
-            ingredientTier := bf.store.GetElementTier(ingredient)
-            if ingredientTier >= resultTier {
-                allIngredientsLowerTier = false
-                break
-            }
-        }
-        
-        if !allIngredientsLowerTier {
-            break // Skip invalid recipe
-        }
-        
         path = append(path, step.Recipe) // Append
         current = step.ParentID
     }
@@ -457,319 +407,6 @@ func (bf *BidirectionalFinder) reconstructBackwardPath(meetingPoint string, chil
     return path
 }
 
-// Find variant path with different search parameters
-func (bf *BidirectionalFinder) findPathWithVariation(target string, variationIndex int) (*SearchResult, error) {
-    startTime := time.Now()
-
-    // Modify search parameters based on variation index
-    // This creates different search patterns to find varied paths
-    
-    // Different variations:
-    // - Use different subsets of basic elements as starting points
-    // - Modify tier prioritization
-    // - Use different randomization seeds for recipe order
-    
-    // For simplicity, we'll implement a basic variation using
-    // different starting elements and search bias
-    
-    // Check target exists
-    _, exists := bf.store.Elements[target]
-    if !exists {
-        return nil, ErrElementNotFound
-    }
-    targetTier := bf.store.GetElementTier(target)
-
-    // Get basic elements
-    basicElements := bf.store.GetBasicElements()
-    if len(basicElements) == 0 {
-        return nil, ErrNoBasicElements
-    }
-
-    // Count visited nodes
-    visitedCount := 0
-
-    // Forward search setup with variation
-    forwardQueue := list.New()
-    forwardVisited := make(map[string]bool)
-    forwardParent := make(map[string]RecipeStep) // Parent tracking
-    forwardTier := make(map[string]int)         // Track tier for each element in forward search
-
-    // Use variation index to select different starting elements
-    startOffset := variationIndex % len(basicElements)
-    elemCount := len(basicElements)
-    
-    // Add basic elements in different order based on variation
-    for i := 0; i < elemCount; i++ {
-        elemIndex := (startOffset + i) % elemCount
-        elem := basicElements[elemIndex]
-        
-        forwardQueue.PushBack(elem.ID)
-        forwardVisited[elem.ID] = true
-        forwardTier[elem.ID] = 0
-        visitedCount++
-    }
-
-    // Backward search setup
-    backwardQueue := list.New()
-    backwardVisited := make(map[string]bool)
-    backwardParent := make(map[string]RecipeStep) // Child tracking
-    backwardTier := make(map[string]int)         // Track tier for each element in backward search
-
-    // Init backward queue
-    backwardQueue.PushBack(target)
-    backwardVisited[target] = true
-    backwardTier[target] = targetTier
-    visitedCount++
-
-    // Meeting point
-    var meetingPoint string
-    found := false
-    
-    // Set variation-specific depth limit to avoid too deep searches
-    maxIterations := 1000 + (variationIndex % 500) // Vary max iterations
-    iterations := 0
-
-    // Run bidirectional BFS with tier constraints
-    for forwardQueue.Len() > 0 && backwardQueue.Len() > 0 && !found && iterations < maxIterations {
-        iterations++
-        
-        // Forward search step with extra randomization based on variation index
-        doBackwardFirst := (variationIndex % 2 == 1) // Alternate search direction
-        
-        if !doBackwardFirst {
-            // Forward search
-            levelSize := forwardQueue.Len()
-            for i := 0; i < levelSize && !found; i++ {
-                current := forwardQueue.Remove(forwardQueue.Front()).(string)
-                currentTier := forwardTier[current]
-                
-                // Check meeting point
-                if backwardVisited[current] {
-                    meetingPoint = current
-                    found = true
-                    break
-                }
-                
-                // Expand forward with tier constraints
-                possibleRecipes := bf.getValidRecipesWithElementAnyPosition(current)
-                
-                // Process recipes in different orders based on variation
-                offset := variationIndex % len(possibleRecipes)
-                if offset > 0 && len(possibleRecipes) > 1 {
-                    possibleRecipes = append(possibleRecipes[offset:], possibleRecipes[:offset]...)
-                }
-                
-                for _, recipe := range possibleRecipes {
-                    resultElem := recipe.Result
-                    resultTier := bf.store.GetElementTier(resultElem)
-                    
-                    // Verify tier constraints
-                    allIngredientsLowerTier := true
-                    for _, ingredient := range recipe.Ingredients {
-                        ingredientTier := bf.store.GetElementTier(ingredient)
-                        if ingredientTier >= resultTier {
-                            allIngredientsLowerTier = false
-                            break
-                        }
-                    }
-                    
-                    if !allIngredientsLowerTier || resultTier <= currentTier {
-                        continue
-                    }
-                    
-                    if !forwardVisited[resultElem] {
-                        forwardQueue.PushBack(resultElem)
-                        forwardVisited[resultElem] = true
-                        forwardTier[resultElem] = resultTier
-                        forwardParent[resultElem] = RecipeStep{
-                            ParentID: current,
-                            Recipe:   recipe,
-                        }
-                        visitedCount++
-                        
-                        if backwardVisited[resultElem] {
-                            meetingPoint = resultElem
-                            found = true
-                            break
-                        }
-                    }
-                }
-            }
-        }
-        
-        if found {
-            break
-        }
-        
-        // Backward search step
-        levelSize := backwardQueue.Len()
-        for i := 0; i < levelSize && !found; i++ {
-            current := backwardQueue.Remove(backwardQueue.Front()).(string)
-            currentTier := backwardTier[current]
-            
-            if forwardVisited[current] {
-                meetingPoint = current
-                found = true
-                break
-            }
-            
-            // Process recipes in different order based on variation
-            relevantRecipes := []Recipe{}
-            for _, recipe := range bf.store.Recipes {
-                if recipe.Result == current {
-                    relevantRecipes = append(relevantRecipes, recipe)
-                }
-            }
-            
-            // Vary recipe order
-            offset := variationIndex % len(relevantRecipes)
-            if offset > 0 && len(relevantRecipes) > 1 {
-                relevantRecipes = append(relevantRecipes[offset:], relevantRecipes[:offset]...)
-            }
-            
-            for _, recipe := range relevantRecipes {
-                // Check tier constraints
-                allIngredientsLowerTier := true
-                for _, ingredient := range recipe.Ingredients {
-                    ingredientTier := bf.store.GetElementTier(ingredient)
-                    if ingredientTier >= currentTier {
-                        allIngredientsLowerTier = false
-                        break
-                    }
-                }
-                
-                if !allIngredientsLowerTier {
-                    continue
-                }
-                
-                // Process ingredients differently based on variation
-                processOrder := recipe.Ingredients
-                if variationIndex%2 == 1 && len(processOrder) > 1 {
-                    // Reverse ingredient processing order in odd variations
-                    processOrder = []string{processOrder[1], processOrder[0]}
-                }
-                
-                for _, ingredient := range processOrder {
-                    ingredientTier := bf.store.GetElementTier(ingredient)
-                    
-                    if ingredientTier >= currentTier {
-                        continue
-                    }
-                    
-                    if !backwardVisited[ingredient] {
-                        backwardQueue.PushBack(ingredient)
-                        backwardVisited[ingredient] = true
-                        backwardTier[ingredient] = ingredientTier
-                        backwardParent[ingredient] = RecipeStep{
-                            ParentID: current,
-                            Recipe:   recipe,
-                        }
-                        visitedCount++
-                        
-                        if forwardVisited[ingredient] {
-                            meetingPoint = ingredient
-                            found = true
-                            break
-                        }
-                    }
-                }
-                
-                if found {
-                    break
-                }
-            }
-        }
-        
-        // If we did forward search first, now do backward
-        if doBackwardFirst {
-            // Forward search (same code as above)
-            levelSize := forwardQueue.Len()
-            for i := 0; i < levelSize && !found; i++ {
-                current := forwardQueue.Remove(forwardQueue.Front()).(string)
-                currentTier := forwardTier[current]
-                
-                // Check meeting point
-                if backwardVisited[current] {
-                    meetingPoint = current
-                    found = true
-                    break
-                }
-                
-                // Expand forward with tier constraints
-                possibleRecipes := bf.getValidRecipesWithElementAnyPosition(current)
-                
-                // Process recipes in different orders based on variation
-                offset := variationIndex % len(possibleRecipes)
-                if offset > 0 && len(possibleRecipes) > 1 {
-                    possibleRecipes = append(possibleRecipes[offset:], possibleRecipes[:offset]...)
-                }
-                
-                for _, recipe := range possibleRecipes {
-                    resultElem := recipe.Result
-                    resultTier := bf.store.GetElementTier(resultElem)
-                    
-                    // Verify tier constraints
-                    allIngredientsLowerTier := true
-                    for _, ingredient := range recipe.Ingredients {
-                        ingredientTier := bf.store.GetElementTier(ingredient)
-                        if ingredientTier >= resultTier {
-                            allIngredientsLowerTier = false
-                            break
-                        }
-                    }
-                    
-                    if !allIngredientsLowerTier || resultTier <= currentTier {
-                        continue
-                    }
-                    
-                    if !forwardVisited[resultElem] {
-                        forwardQueue.PushBack(resultElem)
-                        forwardVisited[resultElem] = true
-                        forwardTier[resultElem] = resultTier
-                        forwardParent[resultElem] = RecipeStep{
-                            ParentID: current,
-                            Recipe:   recipe,
-                        }
-                        visitedCount++
-                        
-                        if backwardVisited[resultElem] {
-                            meetingPoint = resultElem
-                            found = true
-                            break
-                        }
-                    }
-                }
-            }
-        }
-    }
-
-    if !found {
-        return nil, ErrNoPathFound
-    }
-
-    // Build path
-    forwardPath := bf.reconstructForwardPath(meetingPoint, forwardParent)
-    backwardPath := bf.reconstructBackwardPath(meetingPoint, backwardParent)
-
-    // Combine paths
-    var completePath []Recipe
-    completePath = append(completePath, forwardPath...)
-    completePath = append(completePath, backwardPath...)
-
-    // Visualize tree
-    treeStructure := bf.buildTreeStructure(completePath, target)
-
-    executionTime := time.Since(startTime).Milliseconds()
-
-    return &SearchResult{
-        Path:          completePath,
-        VisitedNodes:  visitedCount,
-        ExecutionTime: executionTime,
-        TreeStructure: treeStructure,
-        VariationIndex: variationIndex,
-    }, nil
-}
-
 // Create visualization tree
 func (bf *BidirectionalFinder) buildTreeStructure(path []Recipe, target string) interface{} {
     // Build tree for Next.js visualization - target as root, basics as leaves
@@ -786,13 +423,13 @@ func (bf *BidirectionalFinder) buildTreeStructure(path []Recipe, target string)
     // Add target node
     targetElem := bf.store.Elements[target]
     targetTier := bf.store.GetElementTier(target)
-    nodes = append(nodes, map[string]interface{}{
+    nodes = append(nodes, bf.store.annotateUnlock(map[string]interface{}{
         "id":       target,
         "label":    target,
         "type":     "target",
         "tier":     targetTier,
         "imageUrl": targetElem.ImageURL,
-    })
+    }, target))
     nodeMap[target] = true
     
     // Helper function to recursively build the tree from target to basics
@@ -804,6 +441,9 @@ func (bf *BidirectionalFinder) buildTreeStructure(path []Recipe, target string)
             return
         }
         
+        // Expand each element once, without tiers an ingredient can lead back to it
+        delete(recipesByResult, element)
+        
         // Add ingredients as nodes and connect them with edges
         for _, ingredient := range recipe.Ingredients {
             if !nodeMap[ingredient] {
@@ -815,13 +455,13 @@ func (bf *BidirectionalFinder) buildTreeStructure(path []Recipe, target string)
                     ingType = "basic" // Mark basic elements
                 }
                 
-                nodes = append(nodes, map[string]interface{}{
+                nodes = append(nodes, bf.store.annotateUnlock(map[string]interface{}{
                     "id":       ingredient,
                     "label":    ingredient,
                     "type":     ingType,
                     "tier":     ingTier,
                     "imageUrl": ingElem.ImageURL,
-                })
+                }, ingredient))
                 nodeMap[ingredient] = true
             }
             
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runCommand runs a non-interactive command such as "count Dragon". Searches
+// stop after timeout, if positive, or on Ctrl+C.
+func runCommand(store *ElementStore, args []string, timeout time.Duration) error {
+	switch args[0] {
+	case "count":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: count <element>")
+		}
+		return runCountCommand(store, args[1])
+
+	case "next":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: next <element> [discovered element]...")
+		}
+		return runNextCommand(store, args[1], args[2:], timeout)
+
+	case "from":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: from <element> <target>")
+		}
+		return runFromCommand(store, args[1], args[2], timeout)
+
+	case "plan":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: plan <element> [element]...")
+		}
+		return runPlanCommand(store, args[1:], timeout)
+
+	case "route":
+		if len(args) > 2 || (len(args) == 2 && args[1] != "text") {
+			return fmt.Errorf("usage: route [text]")
+		}
+		route := store.CompletionRoute()
+		if len(args) == 2 {
+			printRoute(route, store)
+			return nil
+		}
+		return printJSON(route)
+
+	case "verify":
+		if len(args) != 2 && len(args) != 3 {
+			return fmt.Errorf("usage: verify <file> [target]")
+		}
+		return runVerifyCommand(store, args[1], args[2:])
+
+	case "bench":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: bench <element> [element]...")
+		}
+		return printJSON(runBenchmarks(store, args[1:]))
+
+	case "stream":
+		if len(args) != 2 && len(args) != 3 {
+			return fmt.Errorf("usage: stream <target> [paths]")
+		}
+		maxPaths := 3
+		if len(args) == 3 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of paths %q", args[2])
+			}
+			maxPaths = n
+		}
+		return runStreamCommand(store, args[1], maxPaths, timeout)
+
+	case "uses":
+		if len(args) != 2 && len(args) != 3 {
+			return fmt.Errorf("usage: uses <element> [element]")
+		}
+		return runUsesCommand(store, args[1:])
+
+	case "report":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: report")
+		}
+		return printJSON(store.Reachability())
+
+	case "tiers":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: tiers")
+		}
+		return printJSON(store.TierDiscrepancies(store.DerivedTiers()))
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runCountCommand prints how many distinct recipe trees exist for target
+func runCountCommand(store *ElementStore, target string) error {
+	count, err := store.CountRecipeTrees(target)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(RecipeTreeCount{
+		Target: target,
+		Tier:   store.GetElementTier(target),
+		Count:  count.String(),
+	})
+}
+
+// InventoryPlan is the JSON form of a plan from discovered elements
+type InventoryPlan struct {
+	Target       string   `json:"target"`
+	Discovered   []string `json:"discovered"`
+	Combinations int      `json:"combinations"`
+	Steps        []Recipe `json:"steps"`           // In the order they should be made
+	Limit        string   `json:"limit,omitempty"` // Search limit that stopped the search, the plan may not be minimal
+	TimedOut     bool     `json:"timedOut,omitempty"`
+}
+
+// runNextCommand prints the fewest combinations left to make target. A search
+// that times out prints the best plan found so far.
+func runNextCommand(store *ElementStore, target string, discovered []string, timeout time.Duration) error {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	result, err := NewMinCombinationFinder(store).FindFromInventoryContext(ctx, target, discovered)
+	if err != nil && !errors.Is(err, ErrSearchTimedOut) {
+		return err
+	}
+
+	return printJSON(InventoryPlan{
+		Target:       target,
+		Discovered:   discovered,
+		Combinations: len(result.Path),
+		Steps:        result.Path,
+		Limit:        result.Limit,
+		TimedOut:     result.TimedOut,
+	})
+}
+
+// ElementDerivation is the JSON form of a derivation from an element the
+// player already has
+type ElementDerivation struct {
+	Start        string   `json:"start"`
+	Target       string   `json:"target"`
+	Combinations int      `json:"combinations"`
+	Steps        []Recipe `json:"steps"` // In the order they should be made
+}
+
+// runFromCommand prints the shortest derivation of target that uses start
+func runFromCommand(store *ElementStore, start, target string, timeout time.Duration) error {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	result, err := NewBreadthFirstFinder(store).FindFromElementContext(ctx, start, target)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(ElementDerivation{
+		Start:        start,
+		Target:       target,
+		Combinations: len(result.Path),
+		Steps:        result.Path,
+	})
+}
+
+// TargetsPlan is the JSON form of one plan for several targets
+type TargetsPlan struct {
+	Targets      []string `json:"targets"`
+	Combinations int      `json:"combinations"`
+	Steps        []Recipe `json:"steps"`           // In the order they should be made
+	Limit        string   `json:"limit,omitempty"` // Search limit that stopped the search, the plan may not be minimal
+	TimedOut     bool     `json:"timedOut,omitempty"`
+}
+
+// runPlanCommand prints the fewest combinations that make every target. A
+// search that times out prints the best plan found so far.
+func runPlanCommand(store *ElementStore, targets []string, timeout time.Duration) error {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	result, err := NewMinCombinationFinder(store).FindForTargetsContext(ctx, targets, nil)
+	if err != nil && !errors.Is(err, ErrSearchTimedOut) {
+		return err
+	}
+
+	return printJSON(TargetsPlan{
+		Targets:      result.Targets,
+		Combinations: len(result.Path),
+		Steps:        result.Path,
+		Limit:        result.Limit,
+		TimedOut:     result.TimedOut,
+	})
+}
+
+// printRoute prints a completion route as a numbered plan
+func printRoute(route *CompletionRoute, store *ElementStore) {
+	fmt.Printf("Completion route: %d combinations discover %d elements\n", route.Combinations, route.Elements)
+	for _, element := range route.Unlocked {
+		fmt.Printf("Unlocked from the start: %s%s\n", element, store.unlockNote(element))
+	}
+
+	for _, step := range route.Steps {
+		fmt.Printf("%d: %s + %s → %s\n", step.Step, step.Ingredients[0], step.Ingredients[1], step.Result)
+		for _, element := range step.Unlocked {
+			fmt.Printf("   Unlocked: %s%s\n", element, store.unlockNote(element))
+		}
+	}
+
+	if len(route.Missed) > 0 {
+		fmt.Printf("%d elements cannot be discovered: %s\n", len(route.Missed), strings.Join(route.Missed, ", "))
+	}
+}
+
+// runVerifyCommand checks a recipe tree or path read from a JSON file. A path
+// is checked against target, or against the result of its last step if no
+// target is given.
+func runVerifyCommand(store *ElementStore, file string, target []string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+
+	var path []Recipe
+	if err := json.Unmarshal(data, &path); err == nil {
+		if len(target) == 0 {
+			if len(path) == 0 {
+				return fmt.Errorf("empty path needs a target")
+			}
+			target = []string{path[len(path)-1].Result}
+		}
+		return printJSON(store.VerifyPath(path, target[0]))
+	}
+
+	var tree TreeNode
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return fmt.Errorf("parsing %s: expected a recipe path or tree: %w", file, err)
+	}
+	verification := store.VerifyTree(&tree)
+	if len(target) > 0 && target[0] != tree.Element {
+		verification.add(0, IssueTargetNotMade, "the tree makes %s, not %s", tree.Element, target[0])
+		verification.Valid = false
+	}
+	return printJSON(verification)
+}
+
+// BenchmarkResult is the outcome of one algorithm searching for one target
+type BenchmarkResult struct {
+	Algorithm    string  `json:"algorithm"`
+	Target       string  `json:"target"`
+	Steps        int     `json:"steps"`
+	VisitedNodes int     `json:"visitedNodes"`
+	Duration     float64 `json:"durationMs"`
+	Speedup      float64 `json:"speedup,omitempty"` // Sequential version's duration over this one, for parallel algorithms
+	Workers      int     `json:"workers,omitempty"` // Most workers a parallel algorithm ran on at once
+	Error        string  `json:"error,omitempty"`
+}
+
+// benchmarkRuns is how many times each search is timed. The fastest run
+// counts, which keeps the speedups of parallel algorithms from being noise.
+const benchmarkRuns = 5
+
+// runBenchmarks times the shortest path search of every registered algorithm
+// for every target, comparing parallel algorithms with their sequential
+// versions
+func runBenchmarks(store *ElementStore, targets []string) []BenchmarkResult {
+	var results []BenchmarkResult
+	algorithms := Finders()
+	for _, target := range targets {
+		benchmarks := make([]BenchmarkResult, len(algorithms))
+		durations := make(map[string]float64)
+		for i, algorithm := range algorithms {
+			benchmarks[i] = benchmarkSearch(store, algorithm, target)
+			durations[algorithm.Name] = benchmarks[i].Duration
+		}
+
+		for i, algorithm := range algorithms {
+			sequential, ok := durations[algorithm.Sequential]
+			if !ok || benchmarks[i].Error != "" || benchmarks[i].Duration == 0 {
+				continue
+			}
+			benchmarks[i].Speedup = sequential / benchmarks[i].Duration
+		}
+		results = append(results, benchmarks...)
+	}
+	return results
+}
+
+// benchmarkSearch times the shortest path search of one algorithm for target
+func benchmarkSearch(store *ElementStore, algorithm FinderInfo, target string) BenchmarkResult {
+	benchmark := BenchmarkResult{Algorithm: algorithm.Name, Target: target}
+	for run := 0; run < benchmarkRuns; run++ {
+		finder := algorithm.New(store)
+		if parallel, ok := finder.(ParallelFinder); ok {
+			parallel.SetWorkers(nil, SearchWorkerLimit)
+		}
+
+		startTime := time.Now()
+		result, err := finder.FindShortestPath(target)
+		duration := float64(time.Since(startTime).Microseconds()) / 1000
+
+		if err != nil {
+			benchmark.Duration = duration
+			benchmark.Error = err.Error()
+			return benchmark
+		}
+		if run == 0 || duration < benchmark.Duration {
+			benchmark.Duration = duration
+		}
+		benchmark.Steps = len(result.Path)
+		benchmark.VisitedNodes = result.VisitedNodes
+		benchmark.Workers = result.Workers
+	}
+	return benchmark
+}
+
+// StreamedPath is one line of the stream command's output
+type StreamedPath struct {
+	Sequence int       `json:"sequence"`
+	Elapsed  float64   `json:"elapsedMs"`
+	Steps    int       `json:"steps"`
+	Cost     float64   `json:"cost"`
+	Path     []Recipe  `json:"path"`
+	Tree     *TreeNode `json:"tree"`
+}
+
+// StreamSummary is the last line of the stream command's output
+type StreamSummary struct {
+	Done     bool    `json:"done"`
+	Paths    int     `json:"paths"`
+	Elapsed  float64 `json:"elapsedMs"`
+	TimedOut bool    `json:"timedOut,omitempty"`
+	Error    string  `json:"error,omitempty"`
+
+	// Recipes left out to break cycles without tiers, trees using them are not listed
+	SkippedRecipes int `json:"skippedRecipes,omitempty"`
+}
+
+// runStreamCommand prints each path for target as one JSON line as soon as
+// it is found, followed by a summary line. The search stops after timeout, if
+// positive, or on Ctrl+C.
+func runStreamCommand(store *ElementStore, target string, maxPaths int, timeout time.Duration) error {
+	finder := NewRankedPathFinder(store)
+	finder.SetWorkers(nil, SearchWorkerLimit)
+
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	encoder := json.NewEncoder(os.Stdout)
+	stream := finder.StreamMultiplePaths(ctx, target, maxPaths)
+
+	summary := StreamSummary{Done: true}
+	for update := range stream.Updates {
+		summary.Paths++
+		summary.SkippedRecipes = update.Result.SkippedRecipes
+		if err := encoder.Encode(StreamedPath{
+			Sequence: update.Sequence,
+			Elapsed:  float64(update.Elapsed.Microseconds()) / 1000,
+			Steps:    len(update.Result.Path),
+			Cost:     update.Result.Cost,
+			Path:     update.Result.Path,
+			Tree:     update.Result.Tree,
+		}); err != nil {
+			cancel()
+		}
+	}
+
+	summary.Elapsed = float64(time.Since(startTime).Microseconds()) / 1000
+	if err := stream.Err(); err != nil {
+		summary.TimedOut = errors.Is(err, ErrSearchTimedOut)
+		summary.Error = err.Error()
+	}
+	return encoder.Encode(summary)
+}
+
+// runUsesCommand prints what can be made from one element or a pair
+func runUsesCommand(store *ElementStore, elements []string) error {
+	query, err := store.FindUses(elements)
+	if err != nil {
+		return err
+	}
+	return printJSON(query)
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrTooManyRequired is returned when more elements are required than a search can track
+var ErrTooManyRequired = errors.New("too many required elements")
+
+// maxRequiredElements is the number of required elements a search can track
+const maxRequiredElements = 64
+
+// SearchConstraints restricts which recipes a search may use. Excluded
+// elements may not appear anywhere in the result, excluded recipes may not be
+// used, and every required element must appear in the result.
+type SearchConstraints struct {
+	ExcludeElements []string
+	ExcludeRecipes  []Recipe // An empty Result matches the ingredients with any result
+	RequireElements []string
+}
+
+// requirements tracks which required elements a partial result has used, one
+// bit per element. A nil requirements has nothing to track.
+type requirements struct {
+	bits map[string]uint64
+	full uint64
+}
+
+// searchState is an element reached with a set of required elements used
+type searchState struct {
+	Element string
+	Mask    uint64
+}
+
+// Validate checks that every constrained element exists in store
+func (c *SearchConstraints) Validate(store *ElementStore) error {
+	if c == nil {
+		return nil
+	}
+
+	for _, list := range [][]string{c.ExcludeElements, c.RequireElements} {
+		for _, element := range list {
+			if _, exists := store.Elements[element]; !exists {
+				return fmt.Errorf("constrained element %q: %w", element, ErrElementNotFound)
+			}
+		}
+	}
+
+	if len(c.RequireElements) > maxRequiredElements {
+		return fmt.Errorf("%w: %d, at most %d", ErrTooManyRequired, len(c.RequireElements), maxRequiredElements)
+	}
+	return nil
+}
+
+// allows reports whether recipe may be used under the constraints
+func (c *SearchConstraints) allows(recipe Recipe, excluded map[string]bool) bool {
+	if excluded[recipe.Result] {
+		return false
+	}
+	for _, ingredient := range recipe.Ingredients {
+		if excluded[ingredient] {
+			return false
+		}
+	}
+
+	for _, banned := range c.ExcludeRecipes {
+		if banned.Result != "" && banned.Result != recipe.Result {
+			continue
+		}
+		if sameIngredients(banned.Ingredients, recipe.Ingredients) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameIngredients compares two pairs of ingredients in any order
+func sameIngredients(a, b []string) bool {
+	if len(a) != 2 || len(b) != 2 {
+		return false
+	}
+	return (a[0] == b[0] && a[1] == b[1]) || (a[0] == b[1] && a[1] == b[0])
+}
+
+// requirements returns the tracker for the required elements
+func (c *SearchConstraints) requirements() *requirements {
+	if c == nil || len(c.RequireElements) == 0 {
+		return nil
+	}
+
+	r := &requirements{bits: make(map[string]uint64)}
+	for _, element := range c.RequireElements {
+		if _, seen := r.bits[element]; seen {
+			continue
+		}
+		bit := uint64(1) << uint(len(r.bits))
+		r.bits[element] = bit
+		r.full |= bit
+	}
+	return r
+}
+
+// add marks the required elements among elements as used
+func (r *requirements) add(mask uint64, elements ...string) uint64 {
+	if r == nil {
+		return mask
+	}
+	for _, element := range elements {
+		mask |= r.bits[element]
+	}
+	return mask
+}
+
+// addRecipe marks the required elements used by recipe
+func (r *requirements) addRecipe(mask uint64, recipe Recipe) uint64 {
+	return r.add(r.add(mask, recipe.Ingredients...), recipe.Result)
+}
+
+// complete reports whether every required element has been used
+func (r *requirements) complete(mask uint64) bool {
+	return r == nil || mask == r.full
+}
+
+// key identifies a search state. Without requirements it is just the element,
+// so unconstrained searches visit each element once as before.
+func (r *requirements) key(state searchState) string {
+	if r == nil {
+		return state.Element
+	}
+	return state.Element + "#" + strconv.FormatUint(state.Mask, 16)
+}
+
+// goalKey is the key of target reached with every required element used
+func (r *requirements) goalKey(target string) string {
+	if r == nil {
+		return target
+	}
+	return r.key(searchState{Element: target, Mask: r.full})
+}
+
+// WithConstraints returns a view of the store without the recipes and basic
+// elements the constraints exclude. Constraints always apply to the original
+// store, so setting new constraints replaces the old ones.
+func (es *ElementStore) WithConstraints(c *SearchConstraints) *ElementStore {
+	base := es
+	if es.unconstrained != nil {
+		base = es.unconstrained
+	}
+	if c == nil {
+		return base
+	}
+
+	excluded := make(map[string]bool)
+	for _, element := range c.ExcludeElements {
+		excluded[element] = true
+	}
+
+	view := &ElementStore{
+		Elements:      base.Elements,
+		Recipes:       []Recipe{},
+		BasicElements: []string{},
+		Locked:        base.Locked,
+		basics:        base.basics,
+		TierMap:       base.TierMap,
+		Dropped:       base.Dropped,
+		tiersIgnored:  base.tiersIgnored,
+		ownedPacks:    base.ownedPacks,
+		unconstrained: base,
+	}
+	for _, recipe := range base.Recipes {
+		if c.allows(recipe, excluded) {
+			view.Recipes = append(view.Recipes, recipe)
+		}
+	}
+	for _, basic := range base.BasicElements {
+		if !excluded[basic] {
+			view.BasicElements = append(view.BasicElements, basic)
+		}
+	}
+
+	return view
+}
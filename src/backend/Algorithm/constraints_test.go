@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequirementsMask(t *testing.T) {
+	tests := []struct {
+		name     string
+		required []string
+		elements []string // Elements used so far
+		wantMask uint64
+		complete bool
+	}{
+		{name: "nothing required", elements: []string{"Mud"}, wantMask: 0, complete: true},
+		{name: "one bit per element", required: []string{"Mud", "Lava"}, elements: []string{"Lava"}, wantMask: 2},
+		{name: "every element used", required: []string{"Mud", "Lava"}, elements: []string{"Lava", "Stone", "Mud"}, wantMask: 3, complete: true},
+		{name: "repeated element counted once", required: []string{"Mud", "Mud", "Lava"}, elements: []string{"Lava"}, wantMask: 2},
+		{name: "other elements ignored", required: []string{"Mud"}, elements: []string{"Earth", "Water"}, wantMask: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraints := &SearchConstraints{RequireElements: tt.required}
+			required := constraints.requirements()
+
+			mask := required.add(0, tt.elements...)
+			if mask != tt.wantMask {
+				t.Errorf("mask = %b, want %b", mask, tt.wantMask)
+			}
+			if got := required.complete(mask); got != tt.complete {
+				t.Errorf("complete(%b) = %v, want %v", mask, got, tt.complete)
+			}
+
+			// Keys only tell masks apart when something is required
+			key := required.key(searchState{Element: "Stone", Mask: mask})
+			if (len(tt.required) == 0) != (key == "Stone") {
+				t.Errorf("key = %q", key)
+			}
+			if goal := required.goalKey("Stone"); tt.complete && len(tt.required) > 0 && goal != key {
+				t.Errorf("goalKey = %q, want the key of the complete mask %q", goal, key)
+			}
+		})
+	}
+}
+
+func TestRequirementsAddRecipe(t *testing.T) {
+	required := (&SearchConstraints{RequireElements: []string{"Mud", "Fire", "Stone"}}).requirements()
+
+	mask := required.addRecipe(0, step("Mud", "Fire", "Stone"))
+	if !required.complete(mask) {
+		t.Errorf("addRecipe marks %b, want the ingredients and result %b", mask, required.full)
+	}
+}
+
+func TestSearchConstraintsValidate(t *testing.T) {
+	tooMany := make([]string, maxRequiredElements+1)
+	for i := range tooMany {
+		tooMany[i] = "Mud"
+	}
+
+	tests := []struct {
+		name        string
+		constraints *SearchConstraints
+		err         error
+	}{
+		{name: "nil constraints"},
+		{name: "known elements", constraints: &SearchConstraints{ExcludeElements: []string{"Lava"}, RequireElements: []string{"Mud"}}},
+		{name: "unknown excluded element", constraints: &SearchConstraints{ExcludeElements: []string{"Gold"}}, err: ErrElementNotFound},
+		{name: "unknown required element", constraints: &SearchConstraints{RequireElements: []string{"Gold"}}, err: ErrElementNotFound},
+		{name: "too many required elements", constraints: &SearchConstraints{RequireElements: tooMany}, err: ErrTooManyRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.constraints.Validate(loadTestStore(t)); !errors.Is(err, tt.err) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestConstrainedTreeEnumeration(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		constraints *SearchConstraints
+		want        []float64 // Step counts of the trees in order
+	}{
+		{name: "unconstrained", target: "Brick", constraints: &SearchConstraints{}, want: []float64{3, 3, 4, 4}},
+		{name: "required element", target: "Brick", constraints: &SearchConstraints{RequireElements: []string{"Lava"}}, want: []float64{3, 4}},
+		{name: "required elements in different branches", target: "Brick", constraints: &SearchConstraints{RequireElements: []string{"Lava", "Mud"}}, want: []float64{4}},
+		{name: "required element deep in the tree", target: "Wall", constraints: &SearchConstraints{RequireElements: []string{"Lava"}}, want: []float64{5, 6}},
+		{name: "required element never used", target: "Brick", constraints: &SearchConstraints{RequireElements: []string{"Cloud"}}},
+		{name: "excluded element", target: "Brick", constraints: &SearchConstraints{ExcludeElements: []string{"Lava"}}, want: []float64{3, 4}},
+		{name: "excluded recipe", target: "Stone", constraints: &SearchConstraints{ExcludeRecipes: []Recipe{{Ingredients: []string{"Fire", "Mud"}}}}, want: []float64{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t).WithConstraints(tt.constraints)
+			trees := NewTreeEnumerator(store, StepsCost{}, tt.constraints).Top(context.Background(), tt.target, 10)
+
+			if len(trees) != len(tt.want) {
+				t.Fatalf("Top(%q) returned %d trees, want %d", tt.target, len(trees), len(tt.want))
+			}
+			for i, tree := range trees {
+				if cost := (StepsCost{}).TreeCost(store, tree); cost != tt.want[i] {
+					t.Errorf("tree %d costs %v, want %v", i, cost, tt.want[i])
+				}
+
+				key := treeKey(tree)
+				for _, element := range tt.constraints.RequireElements {
+					if !strings.Contains(key, element) {
+						t.Errorf("tree %d %s does not use required %s", i, key, element)
+					}
+				}
+				for _, element := range tt.constraints.ExcludeElements {
+					if strings.Contains(key, element) {
+						t.Errorf("tree %d %s uses excluded %s", i, key, element)
+					}
+				}
+			}
+		})
+	}
+}
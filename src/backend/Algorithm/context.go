@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// ErrSearchTimedOut is returned when a search is stopped by the deadline or
+// cancellation of its context. Anything found before that is returned with it.
+var ErrSearchTimedOut = errors.New("search timed out")
+
+// searchStopped returns ErrSearchTimedOut, also wrapping the context's own
+// error, once ctx is done
+func searchStopped(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrSearchTimedOut, err)
+	}
+	return nil
+}
+
+// timedOutResult is the partial result of a single path search that was
+// stopped before it found a path
+func timedOutResult(visitedCount int, startTime time.Time) *SearchResult {
+	return &SearchResult{
+		VisitedNodes:  visitedCount,
+		ExecutionTime: time.Since(startTime).Milliseconds(),
+		TimedOut:      true,
+	}
+}
+
+// searchContext returns the context for one CLI search: it ends after
+// timeout, if positive, or when the user presses Ctrl+C
+func searchContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
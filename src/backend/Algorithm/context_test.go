@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// cancelledContext returns a context that is already done
+func cancelledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestCancelledSearchTimesOut(t *testing.T) {
+	store := loadTestStore(t)
+
+	searches := map[string]func(ctx context.Context) (*SearchResult, error){
+		"recipe tree": func(ctx context.Context) (*SearchResult, error) {
+			return NewRecipeTreeFinder(store).FindShortestPathContext(ctx, "Brick")
+		},
+		"from element": func(ctx context.Context) (*SearchResult, error) {
+			return NewBreadthFirstFinder(store).FindFromElementContext(ctx, "Mud", "Brick")
+		},
+	}
+	for _, info := range Finders() {
+		finder := info.New(store)
+		searches[info.Name] = func(ctx context.Context) (*SearchResult, error) {
+			return finder.FindShortestPathContext(ctx, "Brick")
+		}
+	}
+
+	for name, search := range searches {
+		t.Run(name, func(t *testing.T) {
+			result, err := search(cancelledContext())
+			if !errors.Is(err, ErrSearchTimedOut) || !errors.Is(err, context.Canceled) {
+				t.Fatalf("error = %v, want %v wrapping %v", err, ErrSearchTimedOut, context.Canceled)
+			}
+			if result == nil || !result.TimedOut {
+				t.Fatalf("result = %+v, want a partial result marked as timed out", result)
+			}
+			if len(result.Path) != 0 {
+				t.Errorf("stopped before searching but found path %v", result.Path)
+			}
+		})
+	}
+}
+
+func TestCancelledPlanKeepsBestSoFar(t *testing.T) {
+	store := loadTestStore(t)
+	finder := NewMinCombinationFinder(store)
+
+	result, err := finder.FindShortestPathContext(cancelledContext(), "Wall")
+	if !errors.Is(err, ErrSearchTimedOut) {
+		t.Fatalf("FindShortestPathContext error = %v, want %v", err, ErrSearchTimedOut)
+	}
+	if !result.TimedOut || result.Limit != "" {
+		t.Errorf("TimedOut = %v, Limit = %q, want timed out without an iteration limit", result.TimedOut, result.Limit)
+	}
+	if err := store.VerifyPath(result.Path, "Wall").Err(); err != nil {
+		t.Errorf("plan is invalid: %v", err)
+	}
+
+	targets, err := finder.FindForTargetsContext(cancelledContext(), []string{"Brick", "Pebble"}, nil)
+	if !errors.Is(err, ErrSearchTimedOut) {
+		t.Fatalf("FindForTargetsContext error = %v, want %v", err, ErrSearchTimedOut)
+	}
+	for _, target := range targets.Targets {
+		if err := store.VerifyPath(targets.Path, target).Err(); err != nil {
+			t.Errorf("plan for %s is invalid: %v", target, err)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ErrNegativeWeight is returned for an element weight below zero, which would
+// let a longer tree cost less than a tree it contains
+var ErrNegativeWeight = errors.New("negative element weight")
+
+// CostModel scores a complete recipe tree, lower is better. It ranks multiple
+// paths, and shortest path searches return the cheapest of the paths with the
+// fewest steps.
+type CostModel interface {
+	Name() string
+	TreeCost(store *ElementStore, root *TreeNode) float64
+}
+
+// DecomposableCostModel is a CostModel whose tree cost is built bottom-up from
+// the costs of the ingredient subtrees. Combine must never decrease when an
+// ingredient cost increases, which lets the ranked enumerator optimize the
+// model directly instead of re-ranking trees found by size.
+type DecomposableCostModel interface {
+	CostModel
+	LeafCost(store *ElementStore, element string) float64
+	Combine(store *ElementStore, recipe Recipe, ingredientCosts []float64) float64
+}
+
+// StepsCost counts every combination in the tree
+type StepsCost struct{}
+
+// Name returns the model name
+func (StepsCost) Name() string { return "steps" }
+
+// LeafCost returns the cost of a basic element
+func (StepsCost) LeafCost(store *ElementStore, element string) float64 { return 0 }
+
+// Combine returns the cost of a recipe given its ingredient costs
+func (StepsCost) Combine(store *ElementStore, recipe Recipe, ingredientCosts []float64) float64 {
+	cost := 1.0
+	for _, ingredientCost := range ingredientCosts {
+		cost += ingredientCost
+	}
+	return cost
+}
+
+// TreeCost returns the cost of a whole tree
+func (m StepsCost) TreeCost(store *ElementStore, root *TreeNode) float64 {
+	return decomposedTreeCost(m, store, root)
+}
+
+// DepthCost counts the combinations on the longest branch of the tree
+type DepthCost struct{}
+
+// Name returns the model name
+func (DepthCost) Name() string { return "depth" }
+
+// LeafCost returns the cost of a basic element
+func (DepthCost) LeafCost(store *ElementStore, element string) float64 { return 0 }
+
+// Combine returns the cost of a recipe given its ingredient costs
+func (DepthCost) Combine(store *ElementStore, recipe Recipe, ingredientCosts []float64) float64 {
+	deepest := 0.0
+	for _, ingredientCost := range ingredientCosts {
+		if ingredientCost > deepest {
+			deepest = ingredientCost
+		}
+	}
+	return deepest + 1
+}
+
+// TreeCost returns the cost of a whole tree
+func (m DepthCost) TreeCost(store *ElementStore, root *TreeNode) float64 {
+	return decomposedTreeCost(m, store, root)
+}
+
+// DistinctBasicCost counts the different basic elements used as leaves. It is
+// not decomposable, so searches re-rank the rerankPoolFactor times maxPaths
+// smallest trees with it. The ranking is approximate: a larger tree using
+// fewer basic elements is missed when it falls outside that pool.
+type DistinctBasicCost struct{}
+
+// Name returns the model name
+func (DistinctBasicCost) Name() string { return "basics" }
+
+// TreeCost returns the cost of a whole tree
+func (DistinctBasicCost) TreeCost(store *ElementStore, root *TreeNode) float64 {
+	basics := make(map[string]bool)
+
+	var walk func(node *TreeNode)
+	walk = func(node *TreeNode) {
+		if len(node.Children) == 0 {
+			basics[node.Element] = true
+			return
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+
+	walk(root)
+	return float64(len(basics))
+}
+
+// ElementWeightCost sums a weight for every element made in the tree. Basic
+// leaves cost nothing unless they are given a weight, and other elements
+// without a weight cost DefaultWeight.
+type ElementWeightCost struct {
+	Weights       map[string]float64
+	DefaultWeight float64
+}
+
+// LoadElementWeights reads per-element weights from a JSON object such as
+// {"Time": 10, "Life": 3}
+func LoadElementWeights(path string) (*ElementWeightCost, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading weight file: %w", err)
+	}
+
+	weights := make(map[string]float64)
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return nil, fmt.Errorf("parsing weight JSON: %w", err)
+	}
+	for element, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("%w: %s has %g", ErrNegativeWeight, element, weight)
+		}
+	}
+
+	return &ElementWeightCost{Weights: weights, DefaultWeight: 1}, nil
+}
+
+// Name returns the model name
+func (m *ElementWeightCost) Name() string { return "weights" }
+
+// LeafCost returns the cost of a basic element
+func (m *ElementWeightCost) LeafCost(store *ElementStore, element string) float64 {
+	return m.Weights[element]
+}
+
+// Combine returns the cost of a recipe given its ingredient costs
+func (m *ElementWeightCost) Combine(store *ElementStore, recipe Recipe, ingredientCosts []float64) float64 {
+	cost, exists := m.Weights[recipe.Result]
+	if !exists {
+		cost = m.DefaultWeight
+	}
+	for _, ingredientCost := range ingredientCosts {
+		cost += ingredientCost
+	}
+	return cost
+}
+
+// TreeCost returns the cost of a whole tree
+func (m *ElementWeightCost) TreeCost(store *ElementStore, root *TreeNode) float64 {
+	return decomposedTreeCost(m, store, root)
+}
+
+// decomposedTreeCost evaluates a decomposable model bottom-up over a tree
+func decomposedTreeCost(model DecomposableCostModel, store *ElementStore, node *TreeNode) float64 {
+	if len(node.Children) == 0 {
+		return model.LeafCost(store, node.Element)
+	}
+
+	recipe := Recipe{Result: node.Element}
+	ingredientCosts := make([]float64, 0, len(node.Children))
+	for _, child := range node.Children {
+		recipe.Ingredients = append(recipe.Ingredients, child.Element)
+		ingredientCosts = append(ingredientCosts, decomposedTreeCost(model, store, child))
+	}
+
+	return model.Combine(store, recipe, ingredientCosts)
+}
+
+// chainCost prices the chains shortest path searches build, where every step
+// combines the element made by the step before with an ingredient the chain
+// does not make, step by step as the search extends them. The other
+// ingredient counts as a leaf. Models that are not decomposable cannot price
+// a chain step by step, and steps and depth price every chain of a length the
+// same, so under them every chain costs nothing and searches keep the first
+// of the shortest paths they find.
+type chainCost struct {
+	store *ElementStore
+	model DecomposableCostModel // Nil when chains are not ranked
+}
+
+// newChainCost prices chains under model
+func newChainCost(store *ElementStore, model CostModel) chainCost {
+	switch model.(type) {
+	case StepsCost, DepthCost:
+		return chainCost{store: store}
+	}
+	decomposable, _ := model.(DecomposableCostModel)
+	return chainCost{store: store, model: decomposable}
+}
+
+// ranks reports whether chains of the same length can cost different amounts
+func (c chainCost) ranks() bool {
+	return c.model != nil
+}
+
+// start returns the cost of a chain that has only reached element
+func (c chainCost) start(element string) float64 {
+	if c.model == nil {
+		return 0
+	}
+	return c.model.LeafCost(c.store, element)
+}
+
+// step returns the cost of a chain of cost previous, ending in from, once it
+// is extended with recipe
+func (c chainCost) step(previous float64, from string, recipe Recipe) float64 {
+	if c.model == nil {
+		return 0
+	}
+	ingredientCosts := make([]float64, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		if ingredient == from {
+			ingredientCosts[i] = previous
+		} else {
+			ingredientCosts[i] = c.model.LeafCost(c.store, ingredient)
+		}
+	}
+	return c.model.Combine(c.store, recipe, ingredientCosts)
+}
+
+// path returns the cost of a whole chain
+func (c chainCost) path(path []Recipe) float64 {
+	if len(path) == 0 {
+		return 0
+	}
+	from := path[0].Ingredients[0]
+	cost := c.start(from)
+	for _, recipe := range path {
+		cost = c.step(cost, from, recipe)
+		from = recipe.Result
+	}
+	return cost
+}
+
+// rankByCost orders results by their cost, keeping the enumeration order for
+// ties, and renumbers them
+func rankByCost(results []*SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Cost < results[j].Cost
+	})
+	for i, result := range results {
+		result.VariationIndex = i
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestCostModelPicksShortestPath(t *testing.T) {
+	// Air makes Geyser in one step with Steam or with Mud. Making one of
+	// them dearer must steer every finder to the other.
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		avoided string
+	}{
+		{name: "dear steam", weights: map[string]float64{"Steam": 5}, avoided: "Steam"},
+		{name: "dear mud", weights: map[string]float64{"Mud": 5}, avoided: "Mud"},
+	}
+
+	for _, info := range Finders() {
+		for _, tt := range tests {
+			t.Run(info.Name+"/"+tt.name, func(t *testing.T) {
+				finder := info.New(loadTestStore(t))
+				finder.SetCostModel(&ElementWeightCost{Weights: tt.weights, DefaultWeight: 1})
+
+				result, err := finder.FindShortestPath("Geyser")
+				if err != nil {
+					t.Fatalf("FindShortestPath(Geyser) failed: %v", err)
+				}
+				if len(result.Path) != 1 {
+					t.Fatalf("path has %d steps, want 1: %v", len(result.Path), result.Path)
+				}
+				for _, recipe := range result.Path {
+					for _, ingredient := range recipe.Ingredients {
+						if ingredient == tt.avoided {
+							t.Errorf("path uses %s: %v", tt.avoided, result.Path)
+						}
+					}
+				}
+				if result.Cost != 1 {
+					t.Errorf("path costs %v, want 1", result.Cost)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrUnboundedCount is returned when a cycle of makeable elements makes the
+// number of trees infinite
+var ErrUnboundedCount = errors.New("recipe cycle makes tree count unbounded")
+
+// RecipeTreeCount is the JSON form of a tree count
+type RecipeTreeCount struct {
+	Target string `json:"target"`
+	Tier   int    `json:"tier"`
+	Count  string `json:"count"` // Decimal string, counts overflow JSON numbers
+}
+
+// treeCounter memoizes tree counts per element
+type treeCounter struct {
+	store    *ElementStore
+	recipes  map[string][]Recipe
+	makeable map[string]treeChoice
+	memo     map[string]*big.Int
+	visiting map[string]bool
+}
+
+// CountRecipeTrees counts the distinct complete recipe trees for target, where
+// every leaf is a basic element. Two recipes with the same pair of ingredients
+// are counted once, and swapping the subtrees of a recipe that uses the same
+// ingredient twice does not make a new tree. Recipes that need an element no
+// chain of recipes makes have no trees, so a cycle only makes the count
+// unbounded when every element on it can be made.
+func (es *ElementStore) CountRecipeTrees(target string) (*big.Int, error) {
+	if _, exists := es.Elements[target]; !exists {
+		return nil, ErrElementNotFound
+	}
+
+	counter := &treeCounter{
+		store:    es,
+		recipes:  es.RecipesByResult(),
+		makeable: NewRecipeTreeFinder(es).solve(),
+		memo:     make(map[string]*big.Int),
+		visiting: make(map[string]bool),
+	}
+	return counter.count(target)
+}
+
+// count returns the number of trees for element
+func (tc *treeCounter) count(element string) (*big.Int, error) {
+	if count, exists := tc.memo[element]; exists {
+		return count, nil
+	}
+
+	// Basic elements are leaves with a single tree
+	if tc.store.IsBasicElement(element) {
+		tc.memo[element] = big.NewInt(1)
+		return tc.memo[element], nil
+	}
+
+	// Elements that cannot be made have no trees, even on a cycle
+	if _, ok := tc.makeable[element]; !ok {
+		return new(big.Int), nil
+	}
+
+	// Reaching an element that is still being counted means a cycle
+	if tc.visiting[element] {
+		return nil, ErrUnboundedCount
+	}
+	tc.visiting[element] = true
+	defer delete(tc.visiting, element)
+
+	total := new(big.Int)
+	for _, recipe := range tc.recipes[element] {
+		// A recipe with an ingredient that cannot be made adds nothing, so
+		// do not follow it into a cycle
+		if !tc.canMake(recipe) {
+			continue
+		}
+
+		left, err := tc.count(recipe.Ingredients[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if recipe.Ingredients[0] == recipe.Ingredients[1] {
+			// Unordered pairs of subtrees: n(n+1)/2
+			pairs := new(big.Int).Add(left, big.NewInt(1))
+			pairs.Mul(pairs, left)
+			pairs.Rsh(pairs, 1)
+			total.Add(total, pairs)
+			continue
+		}
+
+		right, err := tc.count(recipe.Ingredients[1])
+		if err != nil {
+			return nil, err
+		}
+		total.Add(total, new(big.Int).Mul(left, right))
+	}
+
+	tc.memo[element] = total
+	return total, nil
+}
+
+// canMake reports whether both ingredients of recipe can be made
+func (tc *treeCounter) canMake(recipe Recipe) bool {
+	for _, ingredient := range recipe.Ingredients {
+		if _, ok := tc.makeable[ingredient]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCountRecipeTrees(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		ignoreTiers bool
+		want        string
+		err         error
+	}{
+		{name: "basic element", target: "Fire", want: "1"},
+		{name: "single recipe", target: "Mud", want: "1"},
+		{name: "duplicate recipe counted once", target: "Steam", want: "1"},
+		{name: "same ingredient twice", target: "Pressure", want: "1"},
+		{name: "recipes add up", target: "Stone", want: "2"},
+		{name: "subtrees multiply", target: "Brick", want: "4"},
+		{name: "swapped subtrees are one tree", target: "Pebble", want: "3"},
+		{name: "no valid recipe", target: "Rain", want: "0"},
+		{name: "unknown element", target: "Gold", err: ErrElementNotFound},
+		{name: "cycle without tiers", target: "Stone", ignoreTiers: true, err: ErrUnboundedCount},
+		{name: "built from a cycle", target: "Pebble", ignoreTiers: true, err: ErrUnboundedCount},
+		{name: "same-tier recipe without tiers", target: "Rain", ignoreTiers: true, want: "1"},
+		{name: "cycle nothing makes", target: "Ghost", ignoreTiers: true, want: "0"},
+		{name: "cycle behind an unmakeable ingredient", target: "Golem", ignoreTiers: true, want: "1"},
+		{name: "unmakeable ingredient with tiers", target: "Golem", want: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+			if tt.ignoreTiers {
+				store.IgnoreTiers()
+			}
+
+			count, err := store.CountRecipeTrees(tt.target)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("CountRecipeTrees(%q) error = %v, want %v", tt.target, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CountRecipeTrees(%q) error = %v", tt.target, err)
+			}
+			if count.String() != tt.want {
+				t.Errorf("CountRecipeTrees(%q) = %s, want %s", tt.target, count, tt.want)
+			}
+		})
+	}
+}
@@ -1,23 +1,70 @@
 package main
 
 import (
+    "context"
     "fmt"
-    "sync"
     "time"
 )
 
 // DepthFirstFinder for recipe search
 type DepthFirstFinder struct {
-    store *ElementStore
+    store              *ElementStore
+    costModel          CostModel
+    constraints        *SearchConstraints
+    iterativeDeepening bool
+    cutOff             bool // Set when the depth limit pruned part of the last search
+}
+
+// dfsBest is the cheapest path to the target an iterative deepening search
+// has found at its current depth limit
+type dfsBest struct {
+    chains chainCost
+    path   []Recipe
+    cost   float64
+    found  bool
+}
+
+func init() {
+    RegisterFinder(FinderInfo{
+        Name:        "dfs",
+        Label:       "DFS",
+        Description: "Depth-First Search (DFS)",
+        Order:       2,
+        New:         func(store *ElementStore) RecipeFinder { return NewDepthFirstFinder(store) },
+    })
 }
 
 // NewDepthFirstFinder creates finder instance
 func NewDepthFirstFinder(store *ElementStore) *DepthFirstFinder {
-    return &DepthFirstFinder{store: store}
+    return &DepthFirstFinder{store: store, costModel: StepsCost{}, iterativeDeepening: true}
+}
+
+// SetCostModel sets the model that picks the cheapest of the paths with the
+// fewest steps. Without iterative deepening the first path found is kept.
+func (df *DepthFirstFinder) SetCostModel(model CostModel) {
+    df.costModel = model
+}
+
+// SetConstraints sets the elements and recipes the search must avoid or use
+func (df *DepthFirstFinder) SetConstraints(constraints *SearchConstraints) {
+    df.constraints = constraints
+    df.store = df.store.WithConstraints(constraints)
+}
+
+// SetIterativeDeepening toggles iterative deepening. When disabled the search
+// runs once with a depth limit of twice the target tier and returns the first
+// path it finds, which need not be the shortest.
+func (df *DepthFirstFinder) SetIterativeDeepening(enabled bool) {
+    df.iterativeDeepening = enabled
 }
 
 // FindShortestPath finds shortest recipe path using DFS
 func (df *DepthFirstFinder) FindShortestPath(target string) (*SearchResult, error) {
+    return df.FindShortestPathContext(context.Background(), target)
+}
+
+// FindShortestPathContext finds shortest recipe path using DFS, stopping once ctx is done
+func (df *DepthFirstFinder) FindShortestPathContext(ctx context.Context, target string) (*SearchResult, error) {
     startTime := time.Now()
 
     // Check target exists
@@ -27,6 +74,12 @@ func (df *DepthFirstFinder) FindShortestPath(target string) (*SearchResult, erro
     }
     targetTier := df.store.GetElementTier(target)
 
+    // Check constraints
+    if err := df.constraints.Validate(df.store); err != nil {
+        return nil, err
+    }
+    required := df.constraints.requirements()
+
     // Get basic elements
     basicElements := df.store.GetBasicElements()
     if len(basicElements) == 0 {
@@ -40,34 +93,71 @@ func (df *DepthFirstFinder) FindShortestPath(target string) (*SearchResult, erro
     visited := make(map[string]bool)
     parent := make(map[string]RecipeStep)
     
-    // Path found flag
+    // Path found flag, and the cheapest path found. Iterative deepening
+    // searches the rest of the depth limit the target was first reached at,
+    // since it may hold a cheaper path of the same length.
     found := false
+    best := &dfsBest{chains: newChainCost(df.store, df.costModel)}
+    ranked := df.iterativeDeepening && best.chains.ranks()
+    
+    // Every step climbs at least one tier, so no path is longer than the target tier
+    maxDepth := targetTier
+    if df.store.TiersIgnored() {
+        maxDepth = len(df.store.Elements) // A path never repeats an element
+    } else if !df.iterativeDeepening {
+        maxDepth = targetTier * 2 // Heuristic: maximum depth is twice the target tier
+    }
     
-    // Initialize with depth limit to prevent infinite recursion
-    maxDepth := targetTier * 2 // Heuristic: maximum depth is twice the target tier
-    
-    // Try each basic element as a starting point
-    for _, elem := range basicElements {
-        visited[elem.ID] = true
-        visitedCount++
-        
-        // Run DFS with depth limit and tier constraints
-        found = df.dfsSearchWithTiers(elem.ID, target, visited, parent, 0, maxDepth, targetTier, &visitedCount)
-        
-        if found {
-            break
+    // Raise the depth limit until a path is found, so the first path is the shortest
+    minDepth := 0
+    if !df.iterativeDeepening {
+        minDepth = maxDepth
+    }
+    for depthLimit := minDepth; depthLimit <= maxDepth && !found; depthLimit++ {
+        // Remaining depth already searched without success. Without tiers a
+        // failure depends on the elements already on the path, so none is kept.
+        var exhausted map[string]int
+        if !df.store.TiersIgnored() {
+            exhausted = make(map[string]int)
         }
+        df.cutOff = false
         
-        // Reset for next basic element
-        delete(visited, elem.ID)
+        // Try each basic element as a starting point
+        for _, elem := range basicElements {
+            visited[elem.ID] = true
+            visitedCount++
+            
+            // Run DFS with depth limit and tier constraints
+            start := searchState{Element: elem.ID, Mask: required.add(0, elem.ID)}
+            found = df.dfsSearchWithTiers(ctx, start, target, required, visited, parent, exhausted, best, ranked, best.chains.start(elem.ID), 0, depthLimit, targetTier, &visitedCount) || found
+            
+            if found && !ranked {
+                break
+            }
+            if err := searchStopped(ctx); err != nil {
+                return timedOutResult(visitedCount, startTime), err
+            }
+            
+            // Reset for next basic element
+            delete(visited, elem.ID)
+        }
     }
     
     if !found {
-        return nil, ErrNoPathFound
+        limit := ""
+        if df.cutOff {
+            limit = fmt.Sprintf("depth limit of %d", maxDepth)
+        }
+        return nil, df.store.explainNoPath(target, df.constraints, limit)
     }
     
     // Build path
-    path := df.reconstructPath(target, parent)
+    path := best.path
+
+    // Paths are chains, so the other ingredient of a step is not made along the way
+    if err := df.store.VerifyPath(path, target).Err(IssueMissingIngredient); err != nil {
+        return nil, err
+    }
     
     // Visualize tree
     treeStructure := df.buildTreeStructure(path, target)
@@ -79,162 +169,134 @@ func (df *DepthFirstFinder) FindShortestPath(target string) (*SearchResult, erro
         VisitedNodes:  visitedCount,
         ExecutionTime: executionTime,
         TreeStructure: treeStructure,
+        Cost:          df.costModel.TreeCost(df.store, BuildRecipeTree(df.store, target, path)),
     }, nil
 }
 
 // DFS search with depth limit and tier constraints
 func (df *DepthFirstFinder) dfsSearchWithTiers(
-    current, target string, 
+    ctx context.Context,
+    state searchState,
+    target string,
+    required *requirements,
     visited map[string]bool, 
     parent map[string]RecipeStep, 
+    exhausted map[string]int,
+    best *dfsBest,
+    ranked bool,
+    cost float64,
     depth, maxDepth int,
     targetTier int,
     visitedCount *int) bool {
     
-    // Check if we found the target
-    if current == target {
+    // Unwind once the search is stopped
+    if ctx.Err() != nil {
+        return false
+    }
+    
+    current := state.Element
+    currentKey := required.key(state)
+    
+    // Check if we found the target, keeping its path when it is the cheapest
+    if current == target && required.complete(state.Mask) {
+        if !best.found || cost < best.cost {
+            best.path = df.reconstructPath(currentKey, parent)
+            best.cost = cost
+            best.found = true
+        }
         return true
     }
     
-    // Check depth limit to prevent infinite recursion
+    // Check depth limit to prevent infinite recursion, only counting it as a
+    // cut off when there was still an unvisited element to step to
     if depth >= maxDepth {
+        for _, recipe := range df.nextRecipes(current, targetTier) {
+            if !visited[recipe.Result] {
+                df.cutOff = true
+                break
+            }
+        }
         return false
     }
     
-    // Get current tier
-    currentTier := df.store.GetElementTier(current)
-    
-    // Get possible recipes using current element that lead to higher tiers
-    possibleRecipes := df.getPossibleRecipesThatRespectTiers(current, currentTier, targetTier)
+    // Skip elements already searched at least this deep without reaching the target
+    remaining := maxDepth - depth
+    if searched, seen := exhausted[currentKey]; seen && searched >= remaining {
+        return false
+    }
     
-    // Try each recipe
-    for _, recipe := range possibleRecipes {
+    // Try each recipe using current element that leads to a higher tier. A
+    // ranked search keeps looking after a path is found.
+    found := false
+    for _, recipe := range df.nextRecipes(current, targetTier) {
         resultElem := recipe.Result
-        resultTier := df.store.GetElementTier(resultElem)
-        
-        // Skip if any ingredient in the recipe doesn't respect tier constraint
-        validRecipe := true
-        for _, ingredient := range recipe.Ingredients {
-            ingredientTier := df.store.GetElementTier(ingredient)
-            if ingredientTier >= resultTier {
-                validRecipe = false
-                break
-            }
-        }
-        
-        if !validRecipe {
-            continue
-        }
-        
-        // Only consider recipes that lead to higher tiers
-        if resultTier <= currentTier {
-            continue
-        }
         
         if !visited[resultElem] {
+            next := searchState{Element: resultElem, Mask: required.addRecipe(state.Mask, recipe)}
+            nextKey := required.key(next)
+            
             // Mark as visited
             visited[resultElem] = true
             *visitedCount++
             
             // Record parent
-            parent[resultElem] = RecipeStep{
-                ParentID: current,
+            parent[nextKey] = RecipeStep{
+                ParentID: currentKey,
                 Recipe:   recipe,
             }
             
             // Recurse deeper
-            if df.dfsSearchWithTiers(resultElem, target, visited, parent, depth+1, maxDepth, targetTier, visitedCount) {
-                return true
+            nextCost := best.chains.step(cost, current, recipe)
+            if df.dfsSearchWithTiers(ctx, next, target, required, visited, parent, exhausted, best, ranked, nextCost, depth+1, maxDepth, targetTier, visitedCount) {
+                found = true
+                if !ranked {
+                    return true
+                }
             }
             
             // Backtrack if needed
             delete(visited, resultElem)
-            delete(parent, resultElem)
+            delete(parent, nextKey)
         }
     }
     
-    return false
-}
-
-// FindMultiplePaths finds multiple recipe paths using multithreading
-func (df *DepthFirstFinder) FindMultiplePaths(target string, maxPaths int) ([]*SearchResult, error) {
-    // Check target exists
-    _, exists := df.store.Elements[target]  // Remove targetElem
-    if !exists {
-        return nil, ErrElementNotFound
-    }
-    targetTier := df.store.GetElementTier(target)
-
-    var results []*SearchResult
-    var mutex sync.Mutex
-    var wg sync.WaitGroup
-
-    // Limit goroutines based on system capabilities
-    maxGoroutines := 4 // Adjust based on system
-    if maxGoroutines > maxPaths {
-        maxGoroutines = maxPaths
+    if exhausted != nil && !found {
+        exhausted[currentKey] = remaining
     }
+    return found
+}
 
-    // Concurrency control
-    sem := make(chan bool, maxGoroutines)
+// nextRecipes returns the recipes the search may step through from elementID
+func (df *DepthFirstFinder) nextRecipes(elementID string, targetTier int) []Recipe {
+    currentTier := df.store.GetElementTier(elementID)
     
-    // First, find the shortest path
-    shortestPath, err := df.FindShortestPath(target)
-    if err != nil {
-        return nil, err
-    }
-    
-    // Add the shortest path to results
-    mutex.Lock()
-    results = append(results, shortestPath)
-    mutex.Unlock()
-    
-    // Track paths we've already found to avoid duplicates
-    pathSignatures := make(map[string]bool)
-    pathSignatureMutex := sync.Mutex{}
-    
-    // Add signature of first path
-    if len(shortestPath.Path) > 0 {
-        sig := getPathSignature(shortestPath.Path)
-        pathSignatureMutex.Lock()
-        pathSignatures[sig] = true
-        pathSignatureMutex.Unlock()
-    }
-
-    // Start multiple searches with different depth limits and starting elements
-    for i := 0; i < maxPaths-1; i++ {
-        wg.Add(1)
-        sem <- true
+    var recipes []Recipe
+    for _, recipe := range df.getPossibleRecipesThatRespectTiers(elementID, currentTier, targetTier) {
+        resultTier := df.store.GetElementTier(recipe.Result)
         
-        go func(index int) {
-            defer wg.Done()
-            defer func() { <-sem }()
-            
-            // Find variation path with different depth limits and starting points
-            result, err := df.findPathVariation(target, index, targetTier, pathSignatures, &pathSignatureMutex)
-            
-            if err == nil && result != nil {
-                mutex.Lock()
-                results = append(results, result)
-                mutex.Unlock()
-                
-                // Add path signature to avoid duplicates
-                sig := getPathSignature(result.Path)
-                pathSignatureMutex.Lock()
-                pathSignatures[sig] = true
-                pathSignatureMutex.Unlock()
+        // Skip if any ingredient in the recipe doesn't respect tier constraint
+        validRecipe := true
+        for _, ingredient := range recipe.Ingredients {
+            ingredientTier := df.store.GetElementTier(ingredient)
+            if !df.store.tierBelow(ingredientTier, resultTier) {
+                validRecipe = false
+                break
             }
-        }(i)
-    }
-    
-    wg.Wait()
-    
-    // Check results
-    if len(results) == 0 {
-        return nil, ErrNoPathFound
+        }
+        
+        if !validRecipe {
+            continue
+        }
+        
+        // Only consider recipes that lead to higher tiers
+        if !df.store.tierBelow(currentTier, resultTier) {
+            continue
+        }
+        
+        recipes = append(recipes, recipe)
     }
     
-    return results, nil
+    return recipes
 }
 
 // Get recipes using element that respect tier hierarchy
@@ -260,20 +322,20 @@ func (df *DepthFirstFinder) getPossibleRecipesThatRespectTiers(elementID string,
         resultTier := df.store.GetElementTier(recipe.Result)
         
         // Skip if result tier is higher than target tier (avoid going beyond what we need)
-        if resultTier > targetTier {
+        if !df.store.tierWithin(resultTier, targetTier) {
             continue
         }
         
         allIngredientsLowerTier := true
         for _, ingredient := range recipe.Ingredients {
             ingredientTier := df.store.GetElementTier(ingredient)
-            if ingredientTier >= resultTier {
+            if !df.store.tierBelow(ingredientTier, resultTier) {
                 allIngredientsLowerTier = false
                 break
             }
         }
         
-        if allIngredientsLowerTier && resultTier > currentTier {
+        if allIngredientsLowerTier && df.store.tierBelow(currentTier, resultTier) {
             recipes = append(recipes, recipe)
         }
     }
@@ -300,204 +362,6 @@ func (df *DepthFirstFinder) reconstructPath(target string, parentMap map[string]
     return path
 }
 
-// Find variant path with different depth limits and starting points
-func (df *DepthFirstFinder) findPathVariation(
-    target string, 
-    variationIndex int,
-    targetTier int,
-    existingPaths map[string]bool,
-    pathMutex *sync.Mutex) (*SearchResult, error) {
-    
-    startTime := time.Now()
-
-    // Get basic elements
-    basicElements := df.store.GetBasicElements()
-    if len(basicElements) == 0 {
-        return nil, ErrNoBasicElements
-    }
-
-    // Visited nodes counter
-    visitedCount := 0
-    
-    // Set up DFS
-    visited := make(map[string]bool)
-    parent := make(map[string]RecipeStep)
-    
-    // Path found flag
-    found := false
-    
-    // Use variation index to adjust depth limit or element priority
-    maxDepth := targetTier * 2 + (variationIndex % 5) // Vary max depth
-    startElemIndex := variationIndex % len(basicElements) // Vary starting element
-    
-    // Reorder basic elements based on variation index
-    reorderedElements := make([]*Element, len(basicElements))
-    copy(reorderedElements, basicElements)
-    
-    // Rotate elements to vary the starting point
-    if startElemIndex > 0 && len(reorderedElements) > 1 {
-        reorderedElements = append(reorderedElements[startElemIndex:], reorderedElements[:startElemIndex]...)
-    }
-    
-    // Try each basic element as a starting point with the new ordering
-    for _, elem := range reorderedElements {
-        visited[elem.ID] = true
-        visitedCount++
-        
-        // Run DFS with depth limit and tier constraints
-        found = df.dfsVariationSearch(
-            elem.ID, 
-            target, 
-            visited, 
-            parent, 
-            0, 
-            maxDepth, 
-            targetTier, 
-            &visitedCount,
-            variationIndex,
-            existingPaths,
-            pathMutex)
-        
-        if found {
-            // Build path and check if it's unique
-            path := df.reconstructPath(target, parent)
-            pathSignature := getPathSignature(path)
-            
-            pathMutex.Lock()
-            pathExists := existingPaths[pathSignature]
-            pathMutex.Unlock()
-            
-            // If path already exists, continue searching with the next element
-            if pathExists {
-                found = false
-                // Reset for next element
-                for k := range parent {
-                    delete(parent, k)
-                }
-                for k := range visited {
-                    delete(visited, k)
-                }
-                visited[elem.ID] = true
-                continue
-            }
-            
-            break
-        }
-        
-        // Reset for next basic element
-        delete(visited, elem.ID)
-    }
-    
-    if !found {
-        return nil, ErrNoPathFound
-    }
-    
-    // Build path
-    path := df.reconstructPath(target, parent)
-    
-    // Visualize tree
-    treeStructure := df.buildTreeStructure(path, target)
-    
-    executionTime := time.Since(startTime).Milliseconds()
-    
-    result := &SearchResult{
-        Path:           path,
-        VisitedNodes:   visitedCount,
-        ExecutionTime:  executionTime,
-        TreeStructure:  treeStructure,
-        VariationIndex: variationIndex,
-    }
-    
-    return result, nil
-}
-
-// DFS variation search to find alternative paths
-func (df *DepthFirstFinder) dfsVariationSearch(
-    current, target string, 
-    visited map[string]bool, 
-    parent map[string]RecipeStep, 
-    depth, maxDepth, targetTier int,
-    visitedCount *int,
-    variationIndex int,
-    existingPaths map[string]bool,
-    pathMutex *sync.Mutex) bool {
-    
-    // Check if we found the target
-    if current == target {
-        return true
-    }
-    
-    // Check depth limit to prevent infinite recursion
-    if depth >= maxDepth {
-        return false
-    }
-    
-    // Get current tier
-    currentTier := df.store.GetElementTier(current)
-    
-    // Get possible recipes using current element that lead to higher tiers
-    possibleRecipes := df.getPossibleRecipesThatRespectTiers(current, currentTier, targetTier)
-    
-    // Add some "randomness" to the recipe order to find different paths
-    if variationIndex > 0 && len(possibleRecipes) > 1 {
-        // Simple shuffle based on variationIndex
-        for i := 0; i < len(possibleRecipes); i++ {
-            swapIndex := (i + variationIndex) % len(possibleRecipes)
-            if swapIndex != i {
-                possibleRecipes[i], possibleRecipes[swapIndex] = possibleRecipes[swapIndex], possibleRecipes[i]
-            }
-        }
-    }
-    
-    // Try each recipe
-    for _, recipe := range possibleRecipes {
-        resultElem := recipe.Result
-        resultTier := df.store.GetElementTier(resultElem)
-        
-        // Skip if any ingredient in the recipe doesn't respect tier constraint
-        validRecipe := true
-        for _, ingredient := range recipe.Ingredients {
-            ingredientTier := df.store.GetElementTier(ingredient)
-            if ingredientTier >= resultTier {
-                validRecipe = false
-                break
-            }
-        }
-        
-        if !validRecipe {
-            continue
-        }
-        
-        // Only consider recipes that lead to higher tiers
-        if resultTier <= currentTier {
-            continue
-        }
-        
-        if !visited[resultElem] {
-            // Mark as visited
-            visited[resultElem] = true
-            *visitedCount++
-            
-            // Record parent
-            parent[resultElem] = RecipeStep{
-                ParentID: current,
-                Recipe:   recipe,
-            }
-            
-            // Recurse deeper
-            if df.dfsVariationSearch(resultElem, target, visited, parent, depth+1, maxDepth, targetTier, visitedCount, variationIndex, existingPaths, pathMutex) {
-                return true
-            }
-            
-            // Backtrack if needed
-            delete(visited, resultElem)
-            delete(parent, resultElem)
-        }
-    }
-    
-    return false
-}
-
 // Create visualization tree
 func (df *DepthFirstFinder) buildTreeStructure(path []Recipe, target string) interface{} {
     // Build tree for Next.js visualization - target as root, basics as leaves
@@ -514,13 +378,13 @@ func (df *DepthFirstFinder) buildTreeStructure(path []Recipe, target string) int
     // Add target node
     targetElem := df.store.Elements[target]
     targetTier := df.store.GetElementTier(target)
-    nodes = append(nodes, map[string]interface{}{
+    nodes = append(nodes, df.store.annotateUnlock(map[string]interface{}{
         "id":       target,
         "label":    target,
         "type":     "target",
         "tier":     targetTier,
         "imageUrl": targetElem.ImageURL,
-    })
+    }, target))
     nodeMap[target] = true
     
     // Helper function to recursively build the tree from target to basics
@@ -532,6 +396,9 @@ func (df *DepthFirstFinder) buildTreeStructure(path []Recipe, target string) int
             return
         }
         
+        // Expand each element once, without tiers an ingredient can lead back to it
+        delete(recipesByResult, element)
+        
         // Add ingredients as nodes and connect them with edges
         for _, ingredient := range recipe.Ingredients {
             if !nodeMap[ingredient] {
@@ -543,13 +410,13 @@ func (df *DepthFirstFinder) buildTreeStructure(path []Recipe, target string) int
                     ingType = "basic" // Mark basic elements
                 }
                 
-                nodes = append(nodes, map[string]interface{}{
+                nodes = append(nodes, df.store.annotateUnlock(map[string]interface{}{
                     "id":       ingredient,
                     "label":    ingredient,
                     "type":     ingType,
                     "tier":     ingTier,
                     "imageUrl": ingElem.ImageURL,
-                })
+                }, ingredient))
                 nodeMap[ingredient] = true
             }
             
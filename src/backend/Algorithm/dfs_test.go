@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIterativeDeepeningShortest(t *testing.T) {
+	for _, ignoreTiers := range []bool{false, true} {
+		store := loadTestStore(t)
+		if ignoreTiers {
+			// Without tiers the depth limit only stops at the element
+			// count, so the search must still end on unreachable targets
+			store.IgnoreTiers()
+		}
+		dfs := NewDepthFirstFinder(store)
+		bfs := NewBreadthFirstFinder(store)
+
+		for target := range store.Elements {
+			want, wantErr := bfs.FindShortestPath(target)
+			got, err := dfs.FindShortestPath(target)
+			if wantErr != nil {
+				if !errors.Is(err, ErrNoPathFound) {
+					t.Errorf("ignoreTiers=%v: FindShortestPath(%q) error = %v, BFS error = %v", ignoreTiers, target, err, wantErr)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("ignoreTiers=%v: FindShortestPath(%q) error = %v", ignoreTiers, target, err)
+			}
+			if len(got.Path) > len(want.Path) {
+				t.Errorf("ignoreTiers=%v: FindShortestPath(%q) has %d steps, BFS has %d", ignoreTiers, target, len(got.Path), len(want.Path))
+			}
+		}
+	}
+}
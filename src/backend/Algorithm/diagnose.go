@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NoPathError explains why a search found no path to Target. It matches
+// ErrNoPathFound, so callers can keep testing for it with errors.Is.
+type NoPathError struct {
+	Target      string          `json:"target"`
+	Recipes     []Recipe        `json:"recipes"`         // Valid recipes for the target
+	Dropped     []DroppedRecipe `json:"dropped"`         // Scraped recipes for the target rejected while loading
+	Excluded    []Recipe        `json:"excluded"`        // Valid recipes ruled out by the search constraints
+	Unreachable []string        `json:"unreachable"`     // Ingredients of the target the basic elements cannot reach
+	Locked      bool            `json:"locked"`          // The target's unlock condition cannot be met
+	Required    []string        `json:"required"`        // Elements the search had to use
+	Limit       string          `json:"limit,omitempty"` // Search limit that cut the search short
+}
+
+// Error summarizes the diagnostic in one line
+func (e *NoPathError) Error() string {
+	var reasons []string
+	if e.Locked {
+		reasons = append(reasons, "its unlock condition cannot be met")
+	}
+	reasons = append(reasons, fmt.Sprintf("%d valid recipes", len(e.Recipes)))
+	if len(e.Dropped) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d dropped while loading", len(e.Dropped)))
+	}
+	if len(e.Excluded) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d excluded by constraints", len(e.Excluded)))
+	}
+	if len(e.Unreachable) > 0 {
+		reasons = append(reasons, "unreachable ingredients "+strings.Join(e.Unreachable, ", "))
+	} else if len(e.Recipes) > 0 && len(e.Required) > 0 {
+		reasons = append(reasons, "no recipe tree uses all of "+strings.Join(e.Required, ", "))
+	}
+	if e.Limit != "" {
+		reasons = append(reasons, "stopped at the "+e.Limit)
+	}
+
+	return fmt.Sprintf("%v for %s: %s", ErrNoPathFound, e.Target, strings.Join(reasons, "; "))
+}
+
+// Unwrap makes a NoPathError match ErrNoPathFound
+func (e *NoPathError) Unwrap() error {
+	return ErrNoPathFound
+}
+
+// explainNoPath diagnoses a search for target that found nothing. The store
+// may be a constrained view, and limit names the search limit that was hit,
+// if any.
+func (es *ElementStore) explainNoPath(target string, constraints *SearchConstraints, limit string) *NoPathError {
+	base := es
+	if es.unconstrained != nil {
+		base = es.unconstrained
+	}
+
+	diagnostic := &NoPathError{
+		Target:      target,
+		Recipes:     []Recipe{},
+		Dropped:     []DroppedRecipe{},
+		Excluded:    []Recipe{},
+		Unreachable: []string{},
+		Required:    []string{},
+		Limit:       limit,
+	}
+	if constraints != nil {
+		diagnostic.Required = append(diagnostic.Required, constraints.RequireElements...)
+	}
+	for _, element := range es.Locked {
+		if element == target {
+			diagnostic.Locked = true
+		}
+	}
+
+	for _, recipe := range base.Dropped {
+		if recipe.Result == target {
+			diagnostic.Dropped = append(diagnostic.Dropped, DroppedRecipe{
+				Ingredients: recipe.Ingredients,
+				Result:      recipe.Result,
+				Reason:      base.dropReason(recipe),
+			})
+		}
+	}
+
+	// Recipes missing from the view were taken out by the constraints
+	recipes := es.RecipesByResult()[target]
+	for _, recipe := range base.RecipesByResult()[target] {
+		allowed := false
+		for _, kept := range recipes {
+			if sameIngredients(kept.Ingredients, recipe.Ingredients) {
+				allowed = true
+				break
+			}
+		}
+		if allowed {
+			diagnostic.Recipes = append(diagnostic.Recipes, recipe)
+		} else {
+			diagnostic.Excluded = append(diagnostic.Excluded, recipe)
+		}
+	}
+
+	reachable := NewRecipeTreeFinder(es).solve()
+	seen := make(map[string]bool)
+	for _, recipe := range diagnostic.Recipes {
+		for _, ingredient := range recipe.Ingredients {
+			if _, ok := reachable[ingredient]; !ok && !seen[ingredient] {
+				seen[ingredient] = true
+				diagnostic.Unreachable = append(diagnostic.Unreachable, ingredient)
+			}
+		}
+	}
+	es.sortByTier(diagnostic.Unreachable)
+
+	return diagnostic
+}
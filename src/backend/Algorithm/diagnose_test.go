@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplainNoPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		constraints *SearchConstraints
+		recipes     int
+		dropped     int
+		excluded    int
+		unreachable []string
+	}{
+		{name: "recipes dropped by tiers", target: "Rain", dropped: 1, unreachable: []string{}},
+		{name: "cycle nothing makes", target: "Ghost", dropped: 1, unreachable: []string{}},
+		{name: "excluded recipe", target: "Lava", constraints: &SearchConstraints{ExcludeRecipes: []Recipe{{Ingredients: []string{"Earth", "Fire"}}}}, excluded: 1, unreachable: []string{}},
+	}
+
+	for _, tt := range tests {
+		for _, info := range Finders() {
+			t.Run(tt.name+"/"+info.Name, func(t *testing.T) {
+				store := loadTestStore(t)
+				finder := info.New(store)
+				finder.SetConstraints(tt.constraints)
+
+				_, err := finder.FindShortestPath(tt.target)
+				if !errors.Is(err, ErrNoPathFound) {
+					t.Fatalf("FindShortestPath(%q) error = %v, want %v", tt.target, err, ErrNoPathFound)
+				}
+				var diagnostic *NoPathError
+				if !errors.As(err, &diagnostic) {
+					t.Fatalf("FindShortestPath(%q) error %v is not a diagnostic", tt.target, err)
+				}
+
+				if len(diagnostic.Recipes) != tt.recipes {
+					t.Errorf("recipes = %v, want %d", diagnostic.Recipes, tt.recipes)
+				}
+				if len(diagnostic.Dropped) != tt.dropped {
+					t.Errorf("dropped = %v, want %d", diagnostic.Dropped, tt.dropped)
+				}
+				if len(diagnostic.Excluded) != tt.excluded {
+					t.Errorf("excluded = %v, want %d", diagnostic.Excluded, tt.excluded)
+				}
+				if !reflect.DeepEqual(diagnostic.Unreachable, tt.unreachable) {
+					t.Errorf("unreachable = %v, want %v", diagnostic.Unreachable, tt.unreachable)
+				}
+			})
+		}
+	}
+}
+
+func TestExplainNoPathUnreachableIngredient(t *testing.T) {
+	store := loadTestStore(t)
+	constraints := &SearchConstraints{ExcludeElements: []string{"Lava"}}
+
+	// Excluding Lava rules out one Golem recipe and leaves one that needs
+	// Ghost. Path finders take the other ingredient of a step as given, so
+	// only a full tree search cannot make Golem.
+	_, err := NewRecipeTreeFinder(store.WithConstraints(constraints)).FindShortestPath("Golem")
+	var diagnostic *NoPathError
+	if !errors.As(err, &diagnostic) {
+		t.Fatalf("FindShortestPath(Golem) error = %v, want a diagnostic", err)
+	}
+	if len(diagnostic.Recipes) != 1 || len(diagnostic.Excluded) != 1 {
+		t.Errorf("recipes = %v and excluded = %v, want one of each", diagnostic.Recipes, diagnostic.Excluded)
+	}
+	if want := []string{"Ghost"}; !reflect.DeepEqual(diagnostic.Unreachable, want) {
+		t.Errorf("unreachable = %v, want %v", diagnostic.Unreachable, want)
+	}
+}
+
+func TestNoPathErrorMessage(t *testing.T) {
+	err := &NoPathError{
+		Target:      "Golem",
+		Recipes:     []Recipe{{Ingredients: []string{"Stone", "Ghost"}, Result: "Golem"}},
+		Unreachable: []string{"Ghost"},
+		Limit:       "depth limit of 3",
+	}
+
+	want := "no path found for Golem: 1 valid recipes; unreachable ingredients Ghost; stopped at the depth limit of 3"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(err.Error(), ErrNoPathFound.Error()) {
+		t.Errorf("Error() = %q does not start with %q", err.Error(), ErrNoPathFound)
+	}
+}
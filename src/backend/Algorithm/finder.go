@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownAlgorithm is returned when no finder is registered under a name
+var ErrUnknownAlgorithm = errors.New("unknown algorithm")
+
+// RecipeFinder is a search algorithm for the shortest recipe path. Every
+// finder picks the cheapest of the shortest paths under a cost model and
+// honours search constraints.
+// FindShortestPathContext stops once the context is done and returns what it
+// found so far with ErrSearchTimedOut. Multiple paths are not a finder's job:
+// RankedPathFinder enumerates them in cost order.
+type RecipeFinder interface {
+	FindShortestPath(target string) (*SearchResult, error)
+	FindShortestPathContext(ctx context.Context, target string) (*SearchResult, error)
+	SetCostModel(model CostModel)
+	SetConstraints(constraints *SearchConstraints)
+}
+
+// ParallelFinder is a finder that spreads its search over a worker pool
+type ParallelFinder interface {
+	RecipeFinder
+	SetWorkers(pool *WorkerPool, limit int)
+}
+
+// FinderInfo describes a registered algorithm
+type FinderInfo struct {
+	Name        string // Registry key, e.g. "bfs"
+	Label       string // Short name for output, e.g. "BFS"
+	Description string // Menu entry, e.g. "Breadth-First Search (BFS)"
+	Order       int    // Position in menus, lowest first
+	Sequential  string // Sequential version of a parallel algorithm, compared in benchmarks
+	New         func(store *ElementStore) RecipeFinder
+}
+
+// finders holds every registered algorithm by name
+var finders = make(map[string]FinderInfo)
+
+// RegisterFinder makes an algorithm available to the CLI, commands and
+// benchmarks. Finders register themselves from init, so registering the same
+// name twice is a programming error.
+func RegisterFinder(info FinderInfo) {
+	if _, exists := finders[info.Name]; exists {
+		panic(fmt.Sprintf("finder %q registered twice", info.Name))
+	}
+	finders[info.Name] = info
+}
+
+// Finders lists the registered algorithms in menu order
+func Finders() []FinderInfo {
+	list := make([]FinderInfo, 0, len(finders))
+	for _, info := range finders {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Order != list[j].Order {
+			return list[i].Order < list[j].Order
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// LookupFinder finds an algorithm by name or by its number in the menu
+func LookupFinder(choice string) (FinderInfo, error) {
+	choice = strings.ToLower(strings.TrimSpace(choice))
+	if info, exists := finders[choice]; exists {
+		return info, nil
+	}
+
+	list := Finders()
+	if number, err := strconv.Atoi(choice); err == nil && number >= 1 && number <= len(list) {
+		return list[number-1], nil
+	}
+	return FinderInfo{}, fmt.Errorf("%w %q", ErrUnknownAlgorithm, choice)
+}
+
+// NewFinder creates the finder registered under name
+func NewFinder(name string, store *ElementStore) (RecipeFinder, error) {
+	info, err := LookupFinder(name)
+	if err != nil {
+		return nil, err
+	}
+	return info.New(store), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFindersInMenuOrder(t *testing.T) {
+	var names []string
+	for _, info := range Finders() {
+		names = append(names, info.Name)
+	}
+
+	want := []string{"bfs", "dfs", "bidirectional", "astar", "bfs-parallel"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Finders() = %v, want %v", names, want)
+	}
+}
+
+func TestLookupFinder(t *testing.T) {
+	tests := []struct {
+		choice string
+		want   string
+		err    error
+	}{
+		{choice: "bfs", want: "bfs"},
+		{choice: " AStar \n", want: "astar"},
+		{choice: "3", want: "bidirectional"},
+		{choice: "0", err: ErrUnknownAlgorithm},
+		{choice: "6", err: ErrUnknownAlgorithm},
+		{choice: "dijkstra", err: ErrUnknownAlgorithm},
+	}
+
+	for _, tt := range tests {
+		info, err := LookupFinder(tt.choice)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("LookupFinder(%q) error = %v, want %v", tt.choice, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LookupFinder(%q) error = %v", tt.choice, err)
+		} else if info.Name != tt.want {
+			t.Errorf("LookupFinder(%q) = %s, want %s", tt.choice, info.Name, tt.want)
+		}
+	}
+}
+
+func TestRegisterFinderTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering bfs again did not panic")
+		}
+	}()
+	RegisterFinder(FinderInfo{Name: "bfs"})
+}
+
+func TestEveryFinderFindsPath(t *testing.T) {
+	store := loadTestStore(t)
+
+	// A registered finder is usable through the interface alone
+	for _, info := range Finders() {
+		finder, err := NewFinder(info.Name, store)
+		if err != nil {
+			t.Fatalf("NewFinder(%q) error = %v", info.Name, err)
+		}
+		result, err := finder.FindShortestPath("Brick")
+		if err != nil {
+			t.Errorf("%s: FindShortestPath(Brick) error = %v", info.Name, err)
+			continue
+		}
+		if err := store.VerifyPath(result.Path, "Brick").Err(IssueMissingIngredient); err != nil {
+			t.Errorf("%s: path is invalid: %v", info.Name, err)
+		}
+	}
+}
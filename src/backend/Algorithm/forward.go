@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ForwardQuery lists what can be made from one element or a pair
+type ForwardQuery struct {
+	Elements   []string     `json:"elements"`
+	Recipes    []Recipe     `json:"recipes"`    // Recipes that use the elements directly
+	Unlockable []UnlockTier `json:"unlockable"` // Every element that can be made from them
+}
+
+// UnlockTier groups unlockable elements of the same tier
+type UnlockTier struct {
+	Tier     int      `json:"tier"`
+	Elements []string `json:"elements"`
+}
+
+// FindUses lists the recipes that use element, or that combine the two
+// elements of a pair, and every element unlockable from their results when
+// the other ingredients can be obtained
+func (es *ElementStore) FindUses(elements []string) (*ForwardQuery, error) {
+	if len(elements) != 1 && len(elements) != 2 {
+		return nil, fmt.Errorf("expected one element or a pair, got %d", len(elements))
+	}
+	for _, element := range elements {
+		if _, exists := es.Elements[element]; !exists {
+			return nil, fmt.Errorf("element %q: %w", element, ErrElementNotFound)
+		}
+	}
+
+	// Recipes using the element, or exactly the pair
+	var recipes []Recipe
+	for _, list := range es.RecipesByResult() {
+		for _, recipe := range list {
+			if len(elements) == 2 && !sameIngredients(elements, recipe.Ingredients) {
+				continue
+			}
+			if len(elements) == 1 && !usesIngredient(recipe, elements[0]) {
+				continue
+			}
+			recipes = append(recipes, recipe)
+		}
+	}
+	es.sortRecipes(recipes)
+
+	// A result is unlocked once every ingredient is known, meaning one of the
+	// elements or something already unlocked, or reachable from the basic
+	// elements. A recipe waiting on an ingredient is tried again when that
+	// ingredient is unlocked, since it is listed under it too.
+	known := make(map[string]bool)
+	for _, element := range elements {
+		known[element] = true
+	}
+	reachable := NewRecipeTreeFinder(es).solve()
+	obtainable := func(recipe Recipe) bool {
+		for _, ingredient := range recipe.Ingredients {
+			if _, ok := reachable[ingredient]; !ok && !known[ingredient] {
+				return false
+			}
+		}
+		return true
+	}
+
+	unlocked := make(map[string]bool)
+	var queue []string
+	unlock := func(recipe Recipe) {
+		if !unlocked[recipe.Result] && obtainable(recipe) {
+			unlocked[recipe.Result] = true
+			known[recipe.Result] = true
+			queue = append(queue, recipe.Result)
+		}
+	}
+	for _, recipe := range recipes {
+		unlock(recipe)
+	}
+
+	// Everything that needs one of the unlocked elements is unlocked too
+	usedBy := es.recipesByIngredient()
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, recipe := range usedBy[current] {
+			unlock(recipe)
+		}
+	}
+	for _, element := range elements {
+		delete(unlocked, element)
+	}
+
+	return &ForwardQuery{
+		Elements:   elements,
+		Recipes:    recipes,
+		Unlockable: es.groupByTier(unlocked),
+	}, nil
+}
+
+// usesIngredient reports whether element is an ingredient of recipe
+func usesIngredient(recipe Recipe, element string) bool {
+	for _, ingredient := range recipe.Ingredients {
+		if ingredient == element {
+			return true
+		}
+	}
+	return false
+}
+
+// recipesByIngredient groups recipes by each element they use
+func (es *ElementStore) recipesByIngredient() map[string][]Recipe {
+	usedBy := make(map[string][]Recipe)
+	for _, recipe := range es.Recipes {
+		for i, ingredient := range recipe.Ingredients {
+			// A recipe using the same ingredient twice is listed once
+			if i > 0 && ingredient == recipe.Ingredients[0] {
+				continue
+			}
+			usedBy[ingredient] = append(usedBy[ingredient], recipe)
+		}
+	}
+	return usedBy
+}
+
+// sortRecipes orders recipes by result tier, then by name, so output is stable
+func (es *ElementStore) sortRecipes(recipes []Recipe) {
+	sort.Slice(recipes, func(i, j int) bool {
+		a, b := recipes[i], recipes[j]
+		if tierA, tierB := es.GetElementTier(a.Result), es.GetElementTier(b.Result); tierA != tierB {
+			return tierA < tierB
+		}
+		if a.Result != b.Result {
+			return a.Result < b.Result
+		}
+		if a.Ingredients[0] != b.Ingredients[0] {
+			return a.Ingredients[0] < b.Ingredients[0]
+		}
+		return a.Ingredients[1] < b.Ingredients[1]
+	})
+}
+
+// groupByTier lists elements grouped by tier, lowest tier first
+func (es *ElementStore) groupByTier(elements map[string]bool) []UnlockTier {
+	byTier := make(map[int][]string)
+	for element := range elements {
+		tier := es.GetElementTier(element)
+		byTier[tier] = append(byTier[tier], element)
+	}
+
+	groups := make([]UnlockTier, 0, len(byTier))
+	for tier, names := range byTier {
+		sort.Strings(names)
+		groups = append(groups, UnlockTier{Tier: tier, Elements: names})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Tier < groups[j].Tier
+	})
+	return groups
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFindUses(t *testing.T) {
+	tests := []struct {
+		name       string
+		elements   []string
+		recipes    []string // Results of the recipes using the elements, in order
+		unlockable []UnlockTier
+		err        error
+	}{
+		{
+			name:     "one element",
+			elements: []string{"Stone"},
+			recipes:  []string{"Brick", "Brick", "Golem", "Pebble"},
+			// Golem also needs Ghost, which nothing makes
+			unlockable: []UnlockTier{{Tier: 3, Elements: []string{"Brick", "Pebble"}}, {Tier: 4, Elements: []string{"Ember", "Wall"}}},
+		},
+		{
+			name:       "pair",
+			elements:   []string{"Fire", "Stone"},
+			recipes:    []string{"Brick"},
+			unlockable: []UnlockTier{{Tier: 3, Elements: []string{"Brick"}}, {Tier: 4, Elements: []string{"Ember", "Wall"}}},
+		},
+		{
+			name:       "element the basics cannot make",
+			elements:   []string{"Ghost"},
+			recipes:    []string{"Golem"},
+			unlockable: []UnlockTier{{Tier: 3, Elements: []string{"Golem"}}},
+		},
+		{name: "unknown element", elements: []string{"Gold"}, err: ErrElementNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+
+			query, err := store.FindUses(tt.elements)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("FindUses(%v) error = %v, want %v", tt.elements, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindUses(%v) error = %v", tt.elements, err)
+			}
+
+			var recipes []string
+			for _, recipe := range query.Recipes {
+				recipes = append(recipes, recipe.Result)
+			}
+			if !reflect.DeepEqual(recipes, tt.recipes) {
+				t.Errorf("recipes make %v, want %v", recipes, tt.recipes)
+			}
+			if !reflect.DeepEqual(query.Unlockable, tt.unlockable) {
+				t.Errorf("unlockable = %v, want %v", query.Unlockable, tt.unlockable)
+			}
+		})
+	}
+}
+
+func TestFindUsesNeedsOneOrTwo(t *testing.T) {
+	store := loadTestStore(t)
+
+	for _, elements := range [][]string{nil, {"Fire", "Water", "Air"}} {
+		if _, err := store.FindUses(elements); err == nil {
+			t.Errorf("FindUses(%v) succeeded, want an error", elements)
+		}
+	}
+}
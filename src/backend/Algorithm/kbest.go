@@ -0,0 +1,566 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// enumNode is an element together with the required elements its trees use.
+// Without required elements the mask is always 0.
+type enumNode struct {
+	Element string
+	Mask    uint64
+}
+
+// derivation is one ranked way to make an element: a recipe plus, for each
+// ingredient, the required elements its tree uses and the rank of that tree.
+// Basic elements have a single derivation without a recipe.
+type derivation struct {
+	Cost        float64
+	RecipeIndex int // Index into the element's recipes, -1 for basic leaves
+	Masks       [2]uint64
+	Ranks       [2]int
+}
+
+// derivationKey identifies a derivation regardless of its cost
+type derivationKey struct {
+	RecipeIndex int
+	Masks       [2]uint64
+	Ranks       [2]int
+}
+
+// candidateHeap orders candidate derivations by cost, then recipe, masks and
+// ranks so that the enumeration is deterministic
+type candidateHeap []derivation
+
+func (h candidateHeap) Len() int { return len(h) }
+
+func (h candidateHeap) Less(i, j int) bool {
+	if h[i].Cost != h[j].Cost {
+		return h[i].Cost < h[j].Cost
+	}
+	if h[i].RecipeIndex != h[j].RecipeIndex {
+		return h[i].RecipeIndex < h[j].RecipeIndex
+	}
+	for side := 0; side < 2; side++ {
+		if h[i].Masks[side] != h[j].Masks[side] {
+			return h[i].Masks[side] < h[j].Masks[side]
+		}
+	}
+	if h[i].Ranks[0] != h[j].Ranks[0] {
+		return h[i].Ranks[0] < h[j].Ranks[0]
+	}
+	return h[i].Ranks[1] < h[j].Ranks[1]
+}
+
+func (h candidateHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *candidateHeap) Push(x interface{}) { *h = append(*h, x.(derivation)) }
+
+func (h *candidateHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// TreeEnumerator yields the complete recipe trees of an element from the
+// cheapest upwards, without duplicates. Derivations are computed lazily, so
+// asking for the first K trees only explores what those K trees need.
+//
+// Trees are ranked separately for every set of required elements they use,
+// so only trees that use every required element are ever enumerated for the
+// target, however many trees without them are cheaper.
+type TreeEnumerator struct {
+	store       *ElementStore
+	model       DecomposableCostModel
+	required    *requirements // Nil when no element is required
+	recipes     map[string][]Recipe
+	edges       map[string]map[uint64][]derivation // Unranked derivations of each element by mask
+	derivations map[enumNode][]derivation
+	candidates  map[enumNode]*candidateHeap
+	queued      map[enumNode]map[derivationKey]bool
+	visiting    map[enumNode]bool
+	visited     int // Number of derivations popped from candidate heaps
+	skipped     int // Recipes left out to break cycles, see acyclicRecipes
+}
+
+// NewTreeEnumerator creates enumerator instance ranking trees by model. The
+// trees of a target use every element constraints require, if any.
+func NewTreeEnumerator(store *ElementStore, model DecomposableCostModel, constraints *SearchConstraints) *TreeEnumerator {
+	recipes, skipped := acyclicRecipes(store)
+	return &TreeEnumerator{
+		store:       store,
+		model:       model,
+		required:    constraints.requirements(),
+		recipes:     recipes,
+		edges:       make(map[string]map[uint64][]derivation),
+		derivations: make(map[enumNode][]derivation),
+		candidates:  make(map[enumNode]*candidateHeap),
+		queued:      make(map[enumNode]map[derivationKey]bool),
+		visiting:    make(map[enumNode]bool),
+		skipped:     skipped,
+	}
+}
+
+// Top returns up to k trees for target, cheapest first. Once ctx is done it
+// returns the trees enumerated so far.
+func (te *TreeEnumerator) Top(ctx context.Context, target string, k int) []*TreeNode {
+	var trees []*TreeNode
+	if k <= 0 {
+		return trees
+	}
+	te.each(ctx, target, k, func(tree *TreeNode) bool {
+		trees = append(trees, tree)
+		return len(trees) < k
+	})
+	return trees
+}
+
+// acyclicRecipes groups the recipes by result and returns how many it left
+// out. With tiers every recipe climbs, so there are no cycles and none is left
+// out. Without tiers only recipes whose ingredients have a smaller cheapest
+// tree than their result are kept, which breaks every cycle while keeping the
+// cheapest tree of each element. Trees that make an ingredient no cheaper
+// than its result are valid too but never enumerated, so without tiers the
+// ranking lists a subset of the trees and reports the skipped recipes.
+func acyclicRecipes(store *ElementStore) (map[string][]Recipe, int) {
+	recipes := store.RecipesByResult()
+	if !store.TiersIgnored() {
+		return recipes, 0
+	}
+
+	skipped := 0
+	costs := NewRecipeTreeFinder(store).solve()
+	for result, list := range recipes {
+		resultCost, ok := costs[result]
+		if !ok {
+			delete(recipes, result)
+			continue
+		}
+
+		kept := list[:0]
+		for _, recipe := range list {
+			climbs := true
+			for _, ingredient := range recipe.Ingredients {
+				if choice, ok := costs[ingredient]; !ok || choice.Cost >= resultCost.Cost {
+					climbs = false
+					break
+				}
+			}
+			if climbs {
+				kept = append(kept, recipe)
+			} else {
+				skipped++
+			}
+		}
+		recipes[result] = kept
+	}
+	return recipes, skipped
+}
+
+// goal returns the node of target's trees that use every required element
+func (te *TreeEnumerator) goal(target string) enumNode {
+	if te.required == nil {
+		return enumNode{Element: target}
+	}
+	return enumNode{Element: target, Mask: te.required.full}
+}
+
+// each calls yield with up to limit trees for target, cheapest first. It
+// stops early when yield returns false or ctx is done.
+func (te *TreeEnumerator) each(ctx context.Context, target string, limit int, yield func(*TreeNode) bool) {
+	goal := te.goal(target)
+	for rank := 0; rank < limit && ctx.Err() == nil; rank++ {
+		if _, ok := te.kth(ctx, goal, rank); !ok {
+			return
+		}
+		if !yield(te.tree(goal, rank)) {
+			return
+		}
+	}
+}
+
+// kth returns the derivation of node with the given rank, computing it and
+// every lower rank if needed. It reports false once ctx is done, leaving the
+// enumeration incomplete, so the enumerator must not be used after that.
+func (te *TreeEnumerator) kth(ctx context.Context, node enumNode, rank int) (derivation, bool) {
+	if !te.initialize(ctx, node) {
+		return derivation{}, false
+	}
+
+	candidates := te.candidates[node]
+	for len(te.derivations[node]) <= rank && candidates != nil && candidates.Len() > 0 && ctx.Err() == nil {
+		next := heap.Pop(candidates).(derivation)
+		te.derivations[node] = append(te.derivations[node], next)
+		te.visited++
+
+		// Queue the neighbours of the popped derivation
+		recipe := te.recipes[node.Element][next.RecipeIndex]
+		sameSubtree := recipe.Ingredients[0] == recipe.Ingredients[1] && next.Masks[0] == next.Masks[1]
+		for side := 0; side < 2; side++ {
+			successor := next
+			successor.Ranks[side]++
+
+			// With the same ingredient and mask twice, only keep ordered rank pairs
+			if sameSubtree && successor.Ranks[0] > successor.Ranks[1] {
+				continue
+			}
+			te.push(ctx, node, successor)
+		}
+	}
+
+	if rank >= len(te.derivations[node]) {
+		return derivation{}, false
+	}
+	return te.derivations[node][rank], true
+}
+
+// initialize seeds the candidate heap of node with the best derivation of
+// each recipe and ingredient masks. It reports false when no tree of the
+// element uses exactly the node's required elements.
+func (te *TreeEnumerator) initialize(ctx context.Context, node enumNode) bool {
+	if _, done := te.derivations[node]; done {
+		return len(te.derivations[node]) > 0 || te.candidates[node].Len() > 0
+	}
+
+	// Basic elements are leaves with a single derivation
+	if te.store.IsBasicElement(node.Element) {
+		te.derivations[node] = []derivation{}
+		if node.Mask == te.required.add(0, node.Element) {
+			te.derivations[node] = append(te.derivations[node], derivation{
+				Cost:        te.model.LeafCost(te.store, node.Element),
+				RecipeIndex: -1,
+			})
+		}
+		return len(te.derivations[node]) > 0
+	}
+
+	// Recipes that lead back to a node being initialized are skipped
+	if te.visiting[node] {
+		return false
+	}
+	te.visiting[node] = true
+	defer delete(te.visiting, node)
+
+	candidates := &candidateHeap{}
+	te.candidates[node] = candidates
+	te.queued[node] = make(map[derivationKey]bool)
+	for _, d := range te.masks(node.Element)[node.Mask] {
+		te.push(ctx, node, d)
+	}
+	te.derivations[node] = []derivation{}
+
+	return candidates.Len() > 0
+}
+
+// masks returns the unranked derivations of element, grouped by the required
+// elements their trees use. Without required elements every derivation uses
+// mask 0.
+func (te *TreeEnumerator) masks(element string) map[uint64][]derivation {
+	if edges, done := te.edges[element]; done {
+		return edges
+	}
+	edges := make(map[uint64][]derivation)
+	te.edges[element] = edges // Guards against cycles until it is filled in
+
+	for index, recipe := range te.recipes[element] {
+		left := te.ingredientMasks(recipe.Ingredients[0])
+		right := te.ingredientMasks(recipe.Ingredients[1])
+		for _, leftMask := range left {
+			for _, rightMask := range right {
+				// The two halves of a recipe with the same ingredient twice
+				// are unordered
+				if recipe.Ingredients[0] == recipe.Ingredients[1] && leftMask > rightMask {
+					continue
+				}
+				mask := te.required.add(leftMask|rightMask, element)
+				edges[mask] = append(edges[mask], derivation{
+					RecipeIndex: index,
+					Masks:       [2]uint64{leftMask, rightMask},
+				})
+			}
+		}
+	}
+	return edges
+}
+
+// ingredientMasks lists the masks element's trees can use, in increasing order
+func (te *TreeEnumerator) ingredientMasks(element string) []uint64 {
+	if te.store.IsBasicElement(element) {
+		return []uint64{te.required.add(0, element)}
+	}
+	edges := te.masks(element)
+	masks := make([]uint64, 0, len(edges))
+	for mask := range edges {
+		masks = append(masks, mask)
+	}
+	sort.Slice(masks, func(i, j int) bool { return masks[i] < masks[j] })
+	return masks
+}
+
+// push queues a derivation of node if both ingredient ranks exist
+func (te *TreeEnumerator) push(ctx context.Context, node enumNode, d derivation) {
+	recipe := te.recipes[node.Element][d.RecipeIndex]
+
+	ingredientCosts := make([]float64, len(recipe.Ingredients))
+	for side, ingredient := range recipe.Ingredients {
+		child, ok := te.kth(ctx, enumNode{Element: ingredient, Mask: d.Masks[side]}, d.Ranks[side])
+		if !ok {
+			return
+		}
+		ingredientCosts[side] = child.Cost
+	}
+
+	key := derivationKey{RecipeIndex: d.RecipeIndex, Masks: d.Masks, Ranks: d.Ranks}
+	if te.queued[node][key] {
+		return
+	}
+	te.queued[node][key] = true
+
+	d.Cost = te.model.Combine(te.store, recipe, ingredientCosts)
+	heap.Push(te.candidates[node], d)
+}
+
+// Tree expands the tree of target with the given rank that uses every
+// required element. The rank must already have been enumerated.
+func (te *TreeEnumerator) Tree(target string, rank int) *TreeNode {
+	return te.tree(te.goal(target), rank)
+}
+
+// tree expands the derivation of node with the given rank into a full recipe
+// tree
+func (te *TreeEnumerator) tree(node enumNode, rank int) *TreeNode {
+	d := te.derivations[node][rank]
+
+	root := &TreeNode{
+		Element:  node.Element,
+		Children: []*TreeNode{},
+		IsResult: d.RecipeIndex >= 0,
+		Tier:     te.store.GetElementTier(node.Element),
+	}
+	if elem, exists := te.store.Elements[node.Element]; exists {
+		root.ImageURL = elem.ImageURL
+	}
+
+	if d.RecipeIndex < 0 {
+		return root
+	}
+
+	recipe := te.recipes[node.Element][d.RecipeIndex]
+	for side, ingredient := range recipe.Ingredients {
+		root.Children = append(root.Children, te.tree(enumNode{Element: ingredient, Mask: d.Masks[side]}, d.Ranks[side]))
+	}
+
+	return root
+}
+
+// rerankPoolFactor is how many more trees are enumerated by size when a cost
+// model cannot be optimized directly. Only that pool is re-ranked, so the
+// results are the cheapest trees among the smallest ones, not necessarily the
+// cheapest overall.
+const rerankPoolFactor = 5
+
+// rankedSearch is a search for the cheapest distinct recipe trees of a
+// target, shared by FindMultiplePathsContext and StreamMultiplePaths
+type rankedSearch struct {
+	store        *ElementStore
+	target       string
+	model        CostModel
+	constraints  *SearchConstraints
+	required     *requirements
+	enumerator   *TreeEnumerator
+	decomposable bool // Set when the enumerator ranks trees by model itself
+	poolSize     int  // Trees to enumerate, more than maxPaths when they are re-ranked
+}
+
+// newRankedSearch checks the target and constraints and sets up the enumerator
+func newRankedSearch(store *ElementStore, target string, maxPaths int, model CostModel, constraints *SearchConstraints) (*rankedSearch, error) {
+	// Check target exists
+	if _, exists := store.Elements[target]; !exists {
+		return nil, ErrElementNotFound
+	}
+
+	// Check constraints
+	if err := constraints.Validate(store); err != nil {
+		return nil, err
+	}
+
+	search := &rankedSearch{
+		store:       store,
+		target:      target,
+		model:       model,
+		constraints: constraints,
+		required:    constraints.requirements(),
+		poolSize:    maxPaths,
+	}
+
+	// Other models re-rank a larger pool of trees enumerated by size
+	enumeratorModel, decomposable := model.(DecomposableCostModel)
+	if !decomposable {
+		enumeratorModel = StepsCost{}
+		search.poolSize = maxPaths * rerankPoolFactor
+	}
+	search.decomposable = decomposable
+	search.enumerator = NewTreeEnumerator(store, enumeratorModel, constraints)
+	return search, nil
+}
+
+// each calls yield with the trees of the pool, cheapest first, until yield
+// returns false or ctx is done. Every tree uses all required elements.
+func (rs *rankedSearch) each(ctx context.Context, yield func(*TreeNode) bool) {
+	rs.enumerator.each(ctx, rs.target, rs.poolSize, yield)
+}
+
+// trees enumerates the pool, or the part of it found before ctx is done
+func (rs *rankedSearch) trees(ctx context.Context) []*TreeNode {
+	var trees []*TreeNode
+	if rs.poolSize <= 0 {
+		return trees
+	}
+	rs.each(ctx, func(root *TreeNode) bool {
+		trees = append(trees, root)
+		return len(trees) < rs.poolSize
+	})
+	return trees
+}
+
+// noPath explains a search that found no tree: it was stopped, or there is
+// none within its limits
+func (rs *rankedSearch) noPath(ctx context.Context) error {
+	if err := searchStopped(ctx); err != nil {
+		return err
+	}
+
+	// Trees through skipped recipes are never enumerated
+	limit := ""
+	if rs.required != nil && rs.enumerator.skipped > 0 {
+		limit = fmt.Sprintf("recipes used without tiers, which leave out %d to break cycles", rs.enumerator.skipped)
+	}
+	return rs.store.explainNoPath(rs.target, rs.constraints, limit)
+}
+
+// result verifies root and turns it into the result with the given rank.
+// visited is the enumerator's count once root was enumerated; results are
+// built on other goroutines while the enumerator runs on.
+func (rs *rankedSearch) result(root *TreeNode, index, visited int, executionTime int64) (*SearchResult, error) {
+	if err := rs.store.VerifyTree(root).Err(); err != nil {
+		return nil, err
+	}
+	return &SearchResult{
+		Path:           RecipeTreeOrder(root),
+		VisitedNodes:   visited,
+		ExecutionTime:  executionTime,
+		TreeStructure:  buildTreeStructureFromNode(rs.store, root),
+		VariationIndex: index,
+		Tree:           root,
+		Cost:           rs.model.TreeCost(rs.store, root),
+		SkippedRecipes: rs.enumerator.skipped,
+	}, nil
+}
+
+// build turns trees into results concurrently on workers, each writing only
+// its own slot so the order never depends on scheduling. Once ctx is done no
+// more trees are handed to workers and their slots are left nil.
+func (rs *rankedSearch) build(ctx context.Context, trees []*TreeNode, workers searchWorkers, executionTime int64) ([]*SearchResult, error) {
+	visited := rs.enumerator.visited
+	results := make([]*SearchResult, len(trees))
+	errs := make([]error, len(trees))
+	workers.run(ctx, len(trees), func(index int) {
+		results[index], errs[index] = rs.result(trees[index], index, visited, executionTime)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// RankedPathFinder finds multiple recipe paths: the cheapest distinct recipe
+// trees of a target under a cost model, enumerated in cost order. The
+// shortest path finders each return a single path.
+type RankedPathFinder struct {
+	store       *ElementStore
+	costModel   CostModel
+	constraints *SearchConstraints
+	workers     searchWorkers // Share of a worker pool results are built on
+}
+
+// NewRankedPathFinder creates finder instance
+func NewRankedPathFinder(store *ElementStore) *RankedPathFinder {
+	return &RankedPathFinder{store: store, costModel: StepsCost{}}
+}
+
+// SetCostModel sets the model paths are ranked by
+func (rf *RankedPathFinder) SetCostModel(model CostModel) {
+	rf.costModel = model
+}
+
+// SetConstraints sets the elements and recipes the paths must avoid or use
+func (rf *RankedPathFinder) SetConstraints(constraints *SearchConstraints) {
+	rf.constraints = constraints
+	rf.store = rf.store.WithConstraints(constraints)
+}
+
+// SetWorkers sets the pool results are built on, nil for the default, and
+// how many of its workers one search may use, 0 for all of them
+func (rf *RankedPathFinder) SetWorkers(pool *WorkerPool, limit int) {
+	rf.workers = searchWorkers{pool: pool, limit: limit}
+}
+
+// FindMultiplePaths finds the maxPaths cheapest distinct recipe trees
+func (rf *RankedPathFinder) FindMultiplePaths(target string, maxPaths int) ([]*SearchResult, error) {
+	return rf.FindMultiplePathsContext(context.Background(), target, maxPaths)
+}
+
+// FindMultiplePathsContext returns the maxPaths cheapest distinct recipe
+// trees for target that use every required element. Trees are enumerated
+// first, then built into results on the search's workers. Once ctx is done no
+// more trees are enumerated or handed to workers, and the results finished so
+// far are returned, marked as timed out, with ErrSearchTimedOut.
+func (rf *RankedPathFinder) FindMultiplePathsContext(ctx context.Context, target string, maxPaths int) ([]*SearchResult, error) {
+	startTime := time.Now()
+
+	search, err := newRankedSearch(rf.store, target, maxPaths, rf.costModel, rf.constraints)
+	if err != nil {
+		return nil, err
+	}
+
+	trees := search.trees(ctx)
+	if len(trees) == 0 {
+		return nil, search.noPath(ctx)
+	}
+	enumerationTime := time.Since(startTime).Milliseconds()
+
+	results, err := search.build(ctx, trees, rf.workers, enumerationTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the results finished before the search was stopped
+	stopped := searchStopped(ctx)
+	if stopped != nil {
+		finished := results[:0]
+		for _, result := range results {
+			if result != nil {
+				result.TimedOut = true
+				finished = append(finished, result)
+			}
+		}
+		results = finished
+	}
+
+	if !search.decomposable {
+		rankByCost(results)
+		if len(results) > maxPaths {
+			results = results[:maxPaths]
+		}
+	}
+
+	return results, stopped
+}
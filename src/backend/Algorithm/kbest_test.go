@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// treeKey writes tree in a canonical form, with the subtrees of every node
+// sorted, so trees that only differ in the order of ingredients share a key
+func treeKey(node *TreeNode) string {
+	if len(node.Children) == 0 {
+		return node.Element
+	}
+
+	children := make([]string, 0, len(node.Children))
+	for _, child := range node.Children {
+		children = append(children, treeKey(child))
+	}
+	sort.Strings(children)
+	return node.Element + "(" + strings.Join(children, ",") + ")"
+}
+
+func TestTreeEnumeratorTop(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		model  DecomposableCostModel
+		k      int
+		want   []float64 // Costs of the trees in order
+	}{
+		{name: "basic element", target: "Fire", model: StepsCost{}, k: 3, want: []float64{0}},
+		{name: "duplicate recipe", target: "Steam", model: StepsCost{}, k: 3, want: []float64{1}},
+		{name: "cheapest first", target: "Brick", model: StepsCost{}, k: 10, want: []float64{3, 3, 4, 4}},
+		{name: "stops at k", target: "Brick", model: StepsCost{}, k: 2, want: []float64{3, 3}},
+		{name: "swapped subtrees", target: "Pebble", model: StepsCost{}, k: 10, want: []float64{5, 5, 5}},
+		{name: "depth model", target: "Brick", model: DepthCost{}, k: 10, want: []float64{3, 3, 3, 3}},
+		{name: "no valid recipe", target: "Rain", model: StepsCost{}, k: 3, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+			trees := NewTreeEnumerator(store, tt.model, nil).Top(context.Background(), tt.target, tt.k)
+
+			if len(trees) != len(tt.want) {
+				t.Fatalf("Top(%q, %d) returned %d trees, want %d", tt.target, tt.k, len(trees), len(tt.want))
+			}
+			seen := make(map[string]bool)
+			for i, tree := range trees {
+				if cost := tt.model.TreeCost(store, tree); cost != tt.want[i] {
+					t.Errorf("tree %d costs %v, want %v", i, cost, tt.want[i])
+				}
+				key := treeKey(tree)
+				if seen[key] {
+					t.Errorf("tree %d repeats %s", i, key)
+				}
+				seen[key] = true
+				if err := store.VerifyTree(tree).Err(); err != nil {
+					t.Errorf("tree %d is invalid: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestTreeEnumeratorCoversCount(t *testing.T) {
+	store := loadTestStore(t)
+
+	for _, target := range []string{"Stone", "Brick", "Pebble"} {
+		count, err := store.CountRecipeTrees(target)
+		if err != nil {
+			t.Fatalf("CountRecipeTrees(%q) error = %v", target, err)
+		}
+
+		// Asking for more trees than exist lists each of them once
+		trees := NewTreeEnumerator(store, StepsCost{}, nil).Top(context.Background(), target, 100)
+		if int64(len(trees)) != count.Int64() {
+			t.Errorf("Top(%q) returned %d trees, want all %s", target, len(trees), count)
+		}
+	}
+}
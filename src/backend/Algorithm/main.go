@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -15,9 +17,14 @@ import (
 
 // Element represents a game element and its recipes
 type Element struct {
-	ID       string     `json:"name"`
-	Recipes  [][]string `json:"recipes"`
-	ImageURL string     `json:"imageUrl"`
+	ID       string           `json:"name"`
+	Recipes  [][]string       `json:"recipes"`
+	ImageURL string           `json:"imageUrl"`
+	Unlock   *UnlockCondition `json:"unlock,omitempty"` // Set for special elements such as Time
+	Pack     string           `json:"pack,omitempty"`   // Expansion pack, empty for base-game content
+
+	// Recipes that need an expansion pack, grouped by pack. They are also listed in Recipes.
+	PackRecipes map[string][][]string `json:"packRecipes,omitempty"`
 }
 
 // ElementGroup represents a group of elements at the same tier
@@ -30,6 +37,7 @@ type ElementGroup struct {
 type Recipe struct {
 	Ingredients []string
 	Result      string
+	Pack        string `json:",omitempty"` // Expansion pack needed, empty for base-game content
 }
 
 // ElementStore holds all element data
@@ -37,7 +45,15 @@ type ElementStore struct {
 	Elements      map[string]*Element
 	Recipes       []Recipe
 	BasicElements []string
-	TierMap       map[string]int // Maps element name to its tier
+	Locked        []string        // Special elements whose unlock condition cannot be met
+	basics        []string        // Tier 0 elements in file order, locked or not
+	TierMap       map[string]int  // Maps element name to its tier
+	Dropped       []Recipe        // Scraped recipes rejected while loading
+	unconstrained *ElementStore   // Original store of a constrained view
+	scraped       []Recipe        // Every scraped recipe in file order
+	scrapedTiers  map[string]int  // Tiers from the wiki headings
+	tiersIgnored  bool            // Set by IgnoreTiers
+	ownedPacks    map[string]bool // Set by RestrictToPacks, nil when every pack is searched
 }
 
 // SearchResult contains search results
@@ -46,7 +62,13 @@ type SearchResult struct {
 	VisitedNodes   int
 	ExecutionTime  int64
 	TreeStructure  interface{}
-	VariationIndex int
+	VariationIndex int       // Rank of the result among multiple paths
+	Tree           *TreeNode // Complete recipe tree, set by tree searches
+	Cost           float64   // Cost under the finder's cost model
+	TimedOut       bool      // Set on the partial result of a search stopped by its context
+	SkippedRecipes int       // Recipes a ranked search without tiers left out to break cycles
+	Workers        int       // Most workers a parallel search ran on at once, 0 for sequential searches
+	Limit          string    // Search limit that cut the search short, so the result may not be the best
 }
 
 // TreeNode represents a node in the recipe tree
@@ -90,6 +112,7 @@ func NewElementStore(jsonFile string) (*ElementStore, error) {
 		Recipes:       []Recipe{},
 		BasicElements: []string{},
 		TierMap:       make(map[string]int),
+		scrapedTiers:  make(map[string]int),
 	}
 
 	// Process elements
@@ -101,45 +124,59 @@ func NewElementStore(jsonFile string) (*ElementStore, error) {
 
 			// Track element tier
 			store.TierMap[elem.ID] = group.TierNum
+			store.scrapedTiers[elem.ID] = group.TierNum
 
 			// Identify basic elements (tier 0)
 			if group.TierNum == 0 {
 				store.BasicElements = append(store.BasicElements, elem.ID)
+				store.basics = append(store.basics, elem.ID)
 			}
 
-			// Process recipes
+			// Keep every recipe, they are checked once all tiers are known
 			for _, ingredients := range elem.Recipes {
-				if len(ingredients) == 2 {
-					// Check if both ingredients have a lower tier than the result
-					allLowerTier := true
-					for _, ingredient := range ingredients {
-						ingTier, exists := store.TierMap[ingredient]
-						if !exists || ingTier >= group.TierNum {
-							allLowerTier = false
-							break
-						}
-					}
-
-					// Only add valid recipes where all ingredients have lower tier
-					if allLowerTier {
-						recipe := Recipe{
-							Ingredients: ingredients,
-							Result:      elem.ID,
-						}
-						store.Recipes = append(store.Recipes, recipe)
-					}
-				}
+				store.scraped = append(store.scraped, Recipe{
+					Ingredients: ingredients,
+					Result:      elem.ID,
+				})
 			}
 		}
 	}
+	store.assignPacks()
+	store.filterRecipes()
+	store.resolveUnlocks()
 
 	// Log summary
-	log.Printf("Loaded %d elements with %d valid recipes", len(store.Elements), len(store.Recipes))
+	log.Printf("Loaded %d elements with %d valid recipes (%d dropped)", len(store.Elements), len(store.Recipes), len(store.Dropped))
 	log.Printf("Basic elements (tier 0): %v", store.BasicElements)
+	if len(store.Locked) > 0 {
+		log.Printf("Locked elements (unlock condition cannot be met): %v", store.Locked)
+	}
 
 	return store, nil
 }
 
+// filterRecipes splits the scraped recipes into valid recipes, with two
+// ingredients of a lower tier than the result from an owned pack, and
+// dropped ones
+func (es *ElementStore) filterRecipes() {
+	es.Recipes = []Recipe{}
+	es.Dropped = nil
+
+	for _, recipe := range es.scraped {
+		if es.searchable(recipe) && es.ValidateTierConstraint(recipe.Ingredients, recipe.Result) {
+			es.Recipes = append(es.Recipes, recipe)
+		} else {
+			es.Dropped = append(es.Dropped, recipe)
+		}
+	}
+}
+
+// searchable reports whether a scraped recipe may be searched whatever the
+// tiers say: it has two ingredients and comes from an owned pack
+func (es *ElementStore) searchable(recipe Recipe) bool {
+	return len(recipe.Ingredients) == 2 && es.packOwned(recipe.Pack)
+}
+
 // GetBasicElements returns basic elements
 func (es *ElementStore) GetBasicElements() []*Element {
 	var basics []*Element
@@ -170,7 +207,8 @@ func (es *ElementStore) GetElementTier(elementID string) int {
 	return tier
 }
 
-// ValidateTierConstraint checks if all ingredients have lower tier than the result
+// ValidateTierConstraint checks if all ingredients have lower tier than the result,
+// or only that they exist when tiers are ignored
 func (es *ElementStore) ValidateTierConstraint(ingredients []string, result string) bool {
 	resultTier, exists := es.TierMap[result]
 	if !exists {
@@ -179,7 +217,7 @@ func (es *ElementStore) ValidateTierConstraint(ingredients []string, result stri
 
 	for _, ingredient := range ingredients {
 		ingredientTier, exists := es.TierMap[ingredient]
-		if !exists || ingredientTier >= resultTier {
+		if !exists || !es.tierBelow(ingredientTier, resultTier) {
 			return false
 		}
 	}
@@ -187,6 +225,33 @@ func (es *ElementStore) ValidateTierConstraint(ingredients []string, result stri
 	return true
 }
 
+// RecipesByResult groups recipes by the element they produce, keeping only
+// one recipe per pair of ingredients
+func (es *ElementStore) RecipesByResult() map[string][]Recipe {
+	recipesByResult := make(map[string][]Recipe)
+	seen := make(map[string]bool)
+
+	for _, recipe := range es.Recipes {
+		if len(recipe.Ingredients) != 2 {
+			continue
+		}
+
+		first, second := recipe.Ingredients[0], recipe.Ingredients[1]
+		if first > second {
+			first, second = second, first
+		}
+		key := recipe.Result + ":" + first + "+" + second
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		recipesByResult[recipe.Result] = append(recipesByResult[recipe.Result], recipe)
+	}
+
+	return recipesByResult
+}
+
 // ListAvailableElements prints a sample of available elements
 func ListAvailableElements(store *ElementStore, maxSample int) {
 	fmt.Println("\nSome available elements:")
@@ -219,22 +284,17 @@ func PrintRecipePath(algorithmName string, result *SearchResult, store *ElementS
 	}
 }
 
-// PrintMultipleRecipePaths prints multiple recipe paths
-func PrintMultipleRecipePaths(algorithmName string, results []*SearchResult, store *ElementStore) {
-	fmt.Printf("\n%s Found %d Different Paths:\n", algorithmName, len(results))
-
-	for i, result := range results {
-		fmt.Printf("\nPath %d (Length: %d, Visited nodes: %d):\n", i+1, len(result.Path), result.VisitedNodes)
-		for j, recipe := range result.Path {
-			tier1 := store.GetElementTier(recipe.Ingredients[0])
-			tier2 := store.GetElementTier(recipe.Ingredients[1])
-			resultTier := store.GetElementTier(recipe.Result)
-			fmt.Printf("  %d: %s (T%d) + %s (T%d) → %s (T%d)\n",
-				j+1,
-				recipe.Ingredients[0], tier1,
-				recipe.Ingredients[1], tier2,
-				recipe.Result, resultTier)
-		}
+// printRecipeSteps prints the numbered steps of one recipe path
+func printRecipeSteps(path []Recipe, store *ElementStore) {
+	for j, recipe := range path {
+		tier1 := store.GetElementTier(recipe.Ingredients[0])
+		tier2 := store.GetElementTier(recipe.Ingredients[1])
+		resultTier := store.GetElementTier(recipe.Result)
+		fmt.Printf("  %d: %s (T%d) + %s (T%d) → %s (T%d)\n",
+			j+1,
+			recipe.Ingredients[0], tier1,
+			recipe.Ingredients[1], tier2,
+			recipe.Result, resultTier)
 	}
 }
 
@@ -255,7 +315,7 @@ func printFullRecipeTree(store *ElementStore, element string, recipeMap map[stri
 	// Format element name based on type
 	var displayName string
 	if store.IsBasicElement(element) {
-		displayName = fmt.Sprintf("%s (T%d, BASIC)", element, tier)
+		displayName = fmt.Sprintf("%s (T%d, BASIC)%s", element, tier, store.unlockNote(element))
 	} else {
 		displayName = fmt.Sprintf("%s (T%d)", element, tier)
 	}
@@ -329,26 +389,11 @@ func BuildRecipeTree(store *ElementStore, target string, path []Recipe) *TreeNod
 
 	// Map to keep track of created nodes
 	nodeMap := make(map[string]*TreeNode)
-
-	// Create root node (target element)
-	targetElem := store.Elements[target]
-	root := &TreeNode{
-		Element:  target,
-		Children: []*TreeNode{},
-		IsResult: true,
-		Tier:     store.GetElementTier(target),
-		ImageURL: targetElem.ImageURL,
-	}
-	nodeMap[target] = root
+	building := make(map[string]bool) // Elements whose ingredients are being added
 
 	// Build the tree top-down (from target to basic elements)
 	var buildTree func(element string) *TreeNode
 	buildTree = func(element string) *TreeNode {
-		// Check if we already created this node
-		if node, exists := nodeMap[element]; exists {
-			return node
-		}
-
 		// Get element details
 		elem, exists := store.Elements[element]
 		if !exists {
@@ -356,6 +401,22 @@ func BuildRecipeTree(store *ElementStore, target string, path []Recipe) *TreeNod
 			return nil
 		}
 
+		// Without tiers a path can lead back to an element that is still
+		// being built, which stays a leaf there
+		if building[element] {
+			return &TreeNode{
+				Element:  element,
+				Children: []*TreeNode{},
+				Tier:     store.GetElementTier(element),
+				ImageURL: elem.ImageURL,
+			}
+		}
+
+		// Check if we already created this node
+		if node, exists := nodeMap[element]; exists {
+			return node
+		}
+
 		// Create new node
 		node := &TreeNode{
 			Element:  element,
@@ -374,20 +435,23 @@ func BuildRecipeTree(store *ElementStore, target string, path []Recipe) *TreeNod
 				recipe := recipes[0]
 
 				// Add ingredient nodes as children
+				building[element] = true
 				for _, ingredient := range recipe.Ingredients {
 					childNode := buildTree(ingredient)
 					if childNode != nil {
 						node.Children = append(node.Children, childNode)
 					}
 				}
+				delete(building, element)
 			}
 		}
 
 		return node
 	}
 
-	// Start building from the target
-	buildTree(target)
+	// Start building from the target, which is always the result
+	root := buildTree(target)
+	root.IsResult = true
 
 	return root
 }
@@ -404,9 +468,18 @@ func PrintRecipeTree(store *ElementStore, target string, path []Recipe) {
 	}
 
 	// Add all recipes from store to cover complete paths
-	for _, recipe := range store.Recipes {
-		if _, exists := recipeMap[recipe.Result]; !exists {
-			recipeMap[recipe.Result] = append(recipeMap[recipe.Result], recipe)
+	if store.TiersIgnored() {
+		// The first recipe may lead around a cycle, use the smallest tree instead
+		for element, choice := range NewRecipeTreeFinder(store).solve() {
+			if _, exists := recipeMap[element]; !exists && !choice.Basic {
+				recipeMap[element] = []Recipe{choice.Recipe}
+			}
+		}
+	} else {
+		for _, recipe := range store.Recipes {
+			if _, exists := recipeMap[recipe.Result]; !exists {
+				recipeMap[recipe.Result] = append(recipeMap[recipe.Result], recipe)
+			}
 		}
 	}
 
@@ -426,7 +499,7 @@ func printTreeNodeSimple(node *TreeNode, prefix string, isLast bool, store *Elem
 	var nodeDisplay string
 	if store.IsBasicElement(node.Element) {
 		// Basic elements in UPPERCASE
-		nodeDisplay = strings.ToUpper(node.Element)
+		nodeDisplay = strings.ToUpper(node.Element) + store.unlockNote(node.Element)
 	} else {
 		// Other elements with tier info
 		nodeDisplay = fmt.Sprintf("%s (T%d)", node.Element, node.Tier)
@@ -449,15 +522,359 @@ func printTreeNodeSimple(node *TreeNode, prefix string, isLast bool, store *Elem
 	}
 }
 
-func main() {
-	// Load elements from JSON file
-	fmt.Println("Loading element data...")
+// runRecipeTreeSearch runs the AND-OR tree search and prints the full tree.
+// The search stops after timeout, if positive, or on Ctrl+C.
+func runRecipeTreeSearch(store *ElementStore, target string, timeout time.Duration) {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	fmt.Println("\nRunning recipe tree search...")
+	startTime := time.Now()
+	tf := NewRecipeTreeFinder(store)
+	treeResult, err := tf.FindShortestPathContext(ctx, target)
+	searchDuration := time.Since(startTime)
+
+	if errors.Is(err, ErrSearchTimedOut) {
+		fmt.Printf("\nRecipe tree search timed out after %v without finding a tree (resolved %d elements)\n",
+			searchDuration, treeResult.VisitedNodes)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Recipe Tree Error: %v\n", err)
+		printNoPathDiagnostic(err)
+		return
+	}
+
+	fmt.Printf("\nRecipe tree search found a tree with %d combinations (%d distinct)!\n",
+		countTreeCombinations(treeResult.Tree), len(treeResult.Path))
+	fmt.Printf("Resolved %d elements during search\n", treeResult.VisitedNodes)
+	fmt.Printf("Algorithm execution time: %d ms\n", treeResult.ExecutionTime)
+	fmt.Printf("Total execution time: %v\n", searchDuration)
+
+	// Print the combinations in the order they can be made
+	PrintRecipePath("Recipe Tree", treeResult, store)
+
+	// Print the full tree
+	fmt.Println("\nRecipe Tree (Target → Basic Elements):")
+	fmt.Println("(Basic elements are in UPPERCASE, other elements show tier in parentheses)")
+	printTreeNodeSimple(treeResult.Tree, "", true, store)
+}
+
+// readCostModel asks which cost model should rank multiple recipe paths, or
+// pick the cheapest of the shortest ones
+func readCostModel(reader *bufio.Reader) CostModel {
+	fmt.Println("\nRank recipe paths by:")
+	fmt.Println("1. Total steps")
+	fmt.Println("2. Tree depth")
+	fmt.Printf("3. Distinct basic elements used (approximate, re-ranks the %dx smallest trees)\n", rerankPoolFactor)
+	fmt.Println("4. Custom element weights from a JSON file")
+	fmt.Print("Enter your choice (1-4, default 1): ")
+
+	choice, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		log.Fatalf("Error reading input: %v", err)
+	}
+
+	switch strings.TrimSpace(choice) {
+	case "2":
+		return DepthCost{}
+	case "3":
+		return DistinctBasicCost{}
+	case "4":
+		fmt.Print("Enter path to the weight file: ")
+		weightPath, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			log.Fatalf("Error reading input: %v", err)
+		}
+
+		weights, err := LoadElementWeights(strings.TrimSpace(weightPath))
+		if err != nil {
+			log.Fatalf("Error loading weights: %v", err)
+		}
+		return weights
+	default:
+		return StepsCost{}
+	}
+}
+
+// loadElementStore loads the scraped element data, optionally replacing the
+// scraped tiers with tiers derived from the recipes or ignoring tiers. A
+// non-nil ownedPacks restricts searches to the base game and those packs.
+func loadElementStore(deriveTiers, ignoreTiers bool, ownedPacks []string) *ElementStore {
 	dataPath := filepath.Join("..", "Scraper", "elements.json")
 	store, err := NewElementStore(dataPath)
 	if err != nil {
 		log.Fatalf("Error loading elements: %v", err)
 	}
 
+	if ownedPacks != nil {
+		if err := store.RestrictToPacks(ownedPacks); err != nil {
+			log.Fatalf("Error restricting packs: %v (known packs: %v)", err, store.KnownPacks())
+		}
+		if len(store.Packs()) == 0 {
+			log.Printf("Warning: elements.json tags no expansion pack content, so every element counts as base game "+
+				"and pack restrictions change nothing; run the scraper again to tag packs %v", expansionPacks)
+		}
+		log.Printf("Searching the base game and packs %v, now %d valid recipes (%d dropped)",
+			ownedPacks, len(store.Recipes), len(store.Dropped))
+	}
+
+	if deriveTiers {
+		changed := store.UseDerivedTiers()
+		log.Printf("Derived tiers differ for %d elements, now %d valid recipes (%d dropped)",
+			len(changed), len(store.Recipes), len(store.Dropped))
+	}
+	if ignoreTiers {
+		store.IgnoreTiers()
+		log.Printf("Ignoring tiers, now %d valid recipes (%d dropped)", len(store.Recipes), len(store.Dropped))
+	}
+	return store
+}
+
+// printNoPathDiagnostic prints why a search found no path as one line of
+// JSON, so the frontend can show more than the error message
+func printNoPathDiagnostic(err error) {
+	var noPath *NoPathError
+	if !errors.As(err, &noPath) {
+		return
+	}
+
+	diagnostic, err := json.Marshal(noPath)
+	if err != nil {
+		log.Printf("Error encoding diagnostic: %v", err)
+		return
+	}
+	fmt.Printf("No path diagnostic: %s\n", diagnostic)
+}
+
+// runMinCombinationSearch finds the plan with the fewest distinct
+// combinations. Once timeout passes, if positive, or on Ctrl+C it prints the
+// best plan found so far.
+func runMinCombinationSearch(store *ElementStore, target string, timeout time.Duration) {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	fmt.Println("\nRunning fewest combinations search...")
+	startTime := time.Now()
+	mf := NewMinCombinationFinder(store)
+	planResult, err := mf.FindShortestPathContext(ctx, target)
+	searchDuration := time.Since(startTime)
+
+	if err != nil && !errors.Is(err, ErrSearchTimedOut) {
+		fmt.Printf("Fewest Combinations Error: %v\n", err)
+		printNoPathDiagnostic(err)
+		return
+	}
+
+	fmt.Printf("\nFewest combinations search found a plan with %d combinations!\n", len(planResult.Path))
+	fmt.Printf("Visited %d nodes during search\n", planResult.VisitedNodes)
+	fmt.Printf("Algorithm execution time: %d ms\n", planResult.ExecutionTime)
+	fmt.Printf("Total execution time: %v\n", searchDuration)
+	printPlanLimit(planResult)
+
+	// Print the combinations in the order they can be made
+	PrintRecipePath("Fewest Combinations", planResult, store)
+
+	// Print the plan as a tree, shared intermediates appear under every user
+	fmt.Println("\nRecipe Tree (Target → Basic Elements):")
+	fmt.Println("(Basic elements are in UPPERCASE, other elements show tier in parentheses)")
+	printTreeNodeSimple(planResult.Tree, "", true, store)
+}
+
+// printPlanLimit warns when the fewest combinations search stopped before it
+// could prove the plan minimal
+func printPlanLimit(result *SearchResult) {
+	if result.Limit != "" {
+		fmt.Printf("Stopped at the %s, the plan may not have the fewest combinations\n", result.Limit)
+	} else if result.TimedOut {
+		fmt.Println("Timed out, the plan is the best found so far and may not have the fewest combinations")
+	}
+}
+
+// readList reads a comma separated list of names
+func readList(reader *bufio.Reader, prompt string) []string {
+	fmt.Print(prompt)
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		log.Fatalf("Error reading input: %v", err)
+	}
+
+	var names []string
+	for _, name := range strings.Split(line, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// readSearchConstraints asks which elements and recipes a search must avoid
+// or use. Recipes are written as two ingredients joined by a plus sign.
+func readSearchConstraints(reader *bufio.Reader) *SearchConstraints {
+	constraints := &SearchConstraints{}
+	for _, name := range readList(reader, "\nElements or recipes to exclude (e.g. Time, Fire+Water, empty for none): ") {
+		if ingredients := strings.Split(name, "+"); len(ingredients) == 2 {
+			constraints.ExcludeRecipes = append(constraints.ExcludeRecipes, Recipe{
+				Ingredients: []string{strings.TrimSpace(ingredients[0]), strings.TrimSpace(ingredients[1])},
+			})
+		} else {
+			constraints.ExcludeElements = append(constraints.ExcludeElements, name)
+		}
+	}
+	constraints.RequireElements = readList(reader, "Elements that must be used (empty for none): ")
+
+	if len(constraints.ExcludeElements) == 0 && len(constraints.ExcludeRecipes) == 0 && len(constraints.RequireElements) == 0 {
+		return nil
+	}
+	return constraints
+}
+
+// runInventorySearch finds the fewest combinations left to make target from
+// the discovered elements, stopping like runMinCombinationSearch
+func runInventorySearch(store *ElementStore, target string, discovered []string, timeout time.Duration) {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	fmt.Println("\nRunning search from discovered elements...")
+	startTime := time.Now()
+	mf := NewMinCombinationFinder(store)
+	planResult, err := mf.FindFromInventoryContext(ctx, target, discovered)
+	searchDuration := time.Since(startTime)
+
+	if err != nil && !errors.Is(err, ErrSearchTimedOut) {
+		fmt.Printf("Discovered Elements Error: %v\n", err)
+		printNoPathDiagnostic(err)
+		return
+	}
+
+	if len(planResult.Path) == 0 {
+		fmt.Printf("\n%s is already discovered!\n", target)
+		return
+	}
+
+	fmt.Printf("\nFound a plan with %d more combinations!\n", len(planResult.Path))
+	fmt.Printf("Visited %d nodes during search\n", planResult.VisitedNodes)
+	fmt.Printf("Algorithm execution time: %d ms\n", planResult.ExecutionTime)
+	fmt.Printf("Total execution time: %v\n", searchDuration)
+	printPlanLimit(planResult)
+
+	// The first combination is what to make next
+	PrintRecipePath("Discovered Elements", planResult, store)
+
+	fmt.Println("\nRecipe Tree (Target → Discovered Elements):")
+	fmt.Println("(Basic elements are in UPPERCASE, other elements show tier in parentheses)")
+	printTreeNodeSimple(planResult.Tree, "", true, store)
+}
+
+// runMultiTargetSearch finds one plan with the fewest combinations for every
+// target, making shared intermediates once and stopping like
+// runMinCombinationSearch
+func runMultiTargetSearch(store *ElementStore, targets []string, timeout time.Duration) {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	fmt.Println("\nRunning multi-target search...")
+	startTime := time.Now()
+	mf := NewMinCombinationFinder(store)
+	planResult, err := mf.FindForTargetsContext(ctx, targets, nil)
+	searchDuration := time.Since(startTime)
+
+	if err != nil && !errors.Is(err, ErrSearchTimedOut) {
+		fmt.Printf("Multi-Target Error: %v\n", err)
+		printNoPathDiagnostic(err)
+		return
+	}
+
+	fmt.Printf("\nFound a plan for %d targets with %d combinations!\n", len(planResult.Targets), len(planResult.Path))
+	fmt.Printf("Visited %d nodes during search\n", planResult.VisitedNodes)
+	fmt.Printf("Algorithm execution time: %d ms\n", planResult.ExecutionTime)
+	fmt.Printf("Total execution time: %v\n", searchDuration)
+	printPlanLimit(&planResult.SearchResult)
+
+	PrintRecipePath("Multi-Target", &planResult.SearchResult, store)
+
+	// Elements shared by several targets show up in each of their trees
+	for i, tree := range planResult.Trees {
+		fmt.Printf("\nRecipe Tree for %s (Target → Basic Elements):\n", planResult.Targets[i])
+		fmt.Println("(Basic elements are in UPPERCASE, other elements show tier in parentheses)")
+		printTreeNodeSimple(tree, "", true, store)
+	}
+}
+
+// runElementSearch finds the shortest derivation of target that starts from
+// an element the player already has. The search stops after timeout, if
+// positive, or on Ctrl+C.
+func runElementSearch(store *ElementStore, start, target string, timeout time.Duration) {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	fmt.Println("\nRunning search from element...")
+	startTime := time.Now()
+	bfs := NewBreadthFirstFinder(store)
+	result, err := bfs.FindFromElementContext(ctx, start, target)
+	searchDuration := time.Since(startTime)
+
+	if errors.Is(err, ErrSearchTimedOut) {
+		fmt.Printf("\nSearch from element timed out after %v without finding a derivation (visited %d nodes)\n",
+			searchDuration, result.VisitedNodes)
+		return
+	}
+	if err != nil {
+		fmt.Printf("From Element Error: %v\n", err)
+		printNoPathDiagnostic(err)
+		return
+	}
+
+	fmt.Printf("\nFound a derivation from %s with %d combinations!\n", start, len(result.Path))
+	fmt.Printf("Visited %d nodes during search\n", result.VisitedNodes)
+	fmt.Printf("Algorithm execution time: %d ms\n", result.ExecutionTime)
+	fmt.Printf("Total execution time: %v\n", searchDuration)
+
+	PrintRecipePath("From Element", result, store)
+
+	fmt.Printf("\nRecipe Tree (Target → %s and Basic Elements):\n", start)
+	fmt.Println("(Basic elements are in UPPERCASE, other elements show tier in parentheses)")
+	printTreeNodeSimple(result.Tree, "", true, store)
+}
+
+func main() {
+	deriveTiers := flag.Bool("derive-tiers", false, "derive element tiers from the recipes instead of the wiki headings")
+	ignoreTiers := flag.Bool("ignore-tiers", false, "search every recipe regardless of tiers")
+	baseGame := flag.Bool("base-game", false, "search base-game content only, plus any packs given with -packs")
+	packs := flag.String("packs", "", "comma separated expansion packs you own; the others are left out of searches")
+	timeout := flag.Duration("timeout", 0, "stop a search after this long and show what it found, e.g. 30s; 0 means no limit")
+	workers := flag.Int("workers", 0, "most goroutines all multi-path searches share; 0 means one per CPU")
+	flag.IntVar(&SearchWorkerLimit, "search-workers", 0, "most of those goroutines one search uses; 0 means all of them")
+	flag.Parse()
+
+	if *workers > 0 {
+		DefaultWorkerPool = NewWorkerPool(*workers)
+	}
+
+	// Without either flag every pack is searched
+	var ownedPacks []string
+	if *baseGame || *packs != "" {
+		ownedPacks = []string{}
+		for _, pack := range strings.Split(*packs, ",") {
+			if pack = strings.TrimSpace(pack); pack != "" {
+				ownedPacks = append(ownedPacks, pack)
+			}
+		}
+	}
+
+	// Commands given on the command line run without prompts and print JSON
+	if flag.NArg() > 0 {
+		store := loadElementStore(*deriveTiers, *ignoreTiers, ownedPacks)
+		if err := runCommand(store, flag.Args(), *timeout); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	// Load elements from JSON file
+	fmt.Println("Loading element data...")
+	store := loadElementStore(*deriveTiers, *ignoreTiers, ownedPacks)
+
 	// Show a sample of available elements
 	ListAvailableElements(store, 10)
 
@@ -482,7 +899,12 @@ func main() {
 	fmt.Println("\nSearch mode:")
 	fmt.Println("1. Find shortest recipe path")
 	fmt.Println("2. Find multiple recipe paths")
-	fmt.Print("Enter your choice (1-2): ")
+	fmt.Println("3. Find complete recipe tree (all ingredients down to basic elements)")
+	fmt.Println("4. Find fewest combinations (shared intermediates are made once)")
+	fmt.Println("5. Continue from elements you have already discovered")
+	fmt.Println("6. Plan several targets at once (shared intermediates are made once)")
+	fmt.Println("7. Start from an element you already have")
+	fmt.Print("Enter your choice (1-7): ")
 
 	searchMode, err := reader.ReadString('\n')
 	if err != nil {
@@ -490,10 +912,63 @@ func main() {
 	}
 	searchMode = strings.TrimSpace(searchMode)
 
-	// Variable for max paths if multiple recipe search is chosen
-	maxPaths := 5 // Default value
+	// The tree search resolves every ingredient, so it needs no algorithm choice
+	if searchMode == "3" {
+		fmt.Printf("\nSearching for a complete recipe tree for: %s (Tier %d)\n",
+			target, store.GetElementTier(target))
+		runRecipeTreeSearch(store, target, *timeout)
+		return
+	}
+
+	if searchMode == "4" {
+		fmt.Printf("\nSearching for the fewest combinations to make: %s (Tier %d)\n",
+			target, store.GetElementTier(target))
+		runMinCombinationSearch(store, target, *timeout)
+		return
+	}
+
+	if searchMode == "5" {
+		discovered := readList(reader, "Enter the elements you have discovered, separated by commas: ")
+		fmt.Printf("\nSearching for what to combine next to make: %s (Tier %d)\n",
+			target, store.GetElementTier(target))
+		runInventorySearch(store, target, discovered, *timeout)
+		return
+	}
+
+	if searchMode == "6" {
+		targets := append([]string{target}, readList(reader, "Enter the other targets, separated by commas: ")...)
+		fmt.Printf("\nSearching for one plan that makes: %s\n", strings.Join(targets, ", "))
+		runMultiTargetSearch(store, targets, *timeout)
+		return
+	}
+
+	if searchMode == "7" {
+		fmt.Print("Enter the element to start from: ")
+		start, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			log.Fatalf("Error reading input: %v", err)
+		}
+		start = strings.TrimSpace(start)
+		fmt.Printf("\nSearching for how %s leads to: %s (Tier %d)\n",
+			start, target, store.GetElementTier(target))
+		runElementSearch(store, start, target, *timeout)
+		return
+	}
 
+	// Multiple paths come from the ranked tree enumeration rather than a path
+	// finder, so there is no algorithm to choose
 	if searchMode == "2" {
+		// Tell the user how many distinct trees exist before asking
+		treeCount, err := store.CountRecipeTrees(target)
+		if errors.Is(err, ErrUnboundedCount) {
+			// Only possible when tiers are ignored
+			fmt.Printf("\nThere are infinitely many ways to make %s\n", target)
+		} else if err != nil {
+			log.Fatalf("Error counting recipe trees: %v", err)
+		} else {
+			fmt.Printf("\nThere are %s ways to make %s\n", treeCount.String(), target)
+		}
+
 		fmt.Print("\nEnter maximum number of recipe paths to find: ")
 		maxPathsInput, err := reader.ReadString('\n')
 		if err != nil {
@@ -501,185 +976,145 @@ func main() {
 		}
 
 		// Parse max paths input
+		maxPaths := 5 // Default value
 		fmt.Sscanf(strings.TrimSpace(maxPathsInput), "%d", &maxPaths)
 		if maxPaths < 1 {
 			maxPaths = 1
-		} else if maxPaths > 20 {
-			maxPaths = 20 // Limit to prevent excessive computation
+		} else if treeCount != nil && treeCount.Sign() > 0 && treeCount.IsInt64() && int64(maxPaths) > treeCount.Int64() {
+			maxPaths = int(treeCount.Int64()) // No more distinct trees exist
 		}
+
+		// Multiple paths are ranked by a cost model
+		costModel := readCostModel(reader)
+		constraints := readSearchConstraints(reader)
+
+		fmt.Printf("\nSearching for recipes to create: %s (Tier %d)\n",
+			target, store.GetElementTier(target))
+
+		finder := NewRankedPathFinder(store)
+		finder.SetCostModel(costModel)
+		finder.SetConstraints(constraints)
+		finder.SetWorkers(nil, SearchWorkerLimit)
+		runMultiplePathSearch(finder, store.WithConstraints(constraints), target, maxPaths, *timeout)
+		return
 	}
 
 	// Get algorithm choice from user
+	algorithms := Finders()
 	fmt.Println("\nSelect algorithm to use:")
-	fmt.Println("1. Breadth-First Search (BFS)")
-	fmt.Println("2. Depth-First Search (DFS)")
-	fmt.Println("3. Bidirectional Search")
-	fmt.Print("Enter your choice (1-3): ")
+	for i, info := range algorithms {
+		fmt.Printf("%d. %s\n", i+1, info.Description)
+	}
+	fmt.Printf("Enter your choice (1-%d): ", len(algorithms))
 
 	algoChoice, err := reader.ReadString('\n')
 	if err != nil {
 		log.Fatalf("Error reading input: %v", err)
 	}
-	algoChoice = strings.TrimSpace(algoChoice)
+	algorithm, err := LookupFinder(algoChoice)
+	if err != nil {
+		fmt.Printf("Invalid choice. Please enter 1-%d or an algorithm name.\n", len(algorithms))
+		os.Exit(1)
+	}
+
+	// The cost model picks among the shortest paths, then optional elements
+	// and recipes to avoid or use
+	costModel := readCostModel(reader)
+	constraints := readSearchConstraints(reader)
+	constrainedStore := store.WithConstraints(constraints)
 
 	fmt.Printf("\nSearching for recipes to create: %s (Tier %d)\n",
 		target, store.GetElementTier(target))
 
-	// Execute the chosen algorithm based on search mode
-	if searchMode == "1" {
-		// Single recipe path search
-		switch algoChoice {
-		case "1":
-			// Run BFS search
-			fmt.Println("\nRunning BFS search...")
-			startTime := time.Now()
-			bfs := NewBreadthFirstFinder(store)
-			bfsResult, err := bfs.FindShortestPath(target)
-			searchDuration := time.Since(startTime)
-
-			if err != nil {
-				fmt.Printf("BFS Error: %v\n", err)
-			} else {
-				fmt.Printf("\nBFS found a path with %d steps!\n", len(bfsResult.Path))
-				fmt.Printf("Visited %d nodes during search\n", bfsResult.VisitedNodes)
-				fmt.Printf("Algorithm execution time: %d ms\n", bfsResult.ExecutionTime)
-				fmt.Printf("Total execution time: %v\n", searchDuration)
-
-				// Print recipe path
-				PrintRecipePath("BFS", bfsResult, store)
-
-				// Print recipe tree
-				PrintRecipeTree(store, target, bfsResult.Path)
-			}
+	finder := algorithm.New(store)
+	finder.SetCostModel(costModel)
+	finder.SetConstraints(constraints)
+	if parallel, ok := finder.(ParallelFinder); ok {
+		parallel.SetWorkers(nil, SearchWorkerLimit)
+	}
+	runShortestPathSearch(algorithm.Label, finder, constrainedStore, target, *timeout)
+}
 
-		case "2":
-			// Run DFS search
-			fmt.Println("\nRunning DFS search...")
-			startTime := time.Now()
-			dfs := NewDepthFirstFinder(store)
-			dfsResult, err := dfs.FindShortestPath(target)
-			searchDuration := time.Since(startTime)
-
-			if err != nil {
-				fmt.Printf("DFS Error: %v\n", err)
-			} else {
-				fmt.Printf("\nDFS found a path with %d steps!\n", len(dfsResult.Path))
-				fmt.Printf("Visited %d nodes during search\n", dfsResult.VisitedNodes)
-				fmt.Printf("Algorithm execution time: %d ms\n", dfsResult.ExecutionTime)
-				fmt.Printf("Total execution time: %v\n", searchDuration)
-
-				// Print recipe path
-				PrintRecipePath("DFS", dfsResult, store)
-
-				// Print recipe tree
-				PrintRecipeTree(store, target, dfsResult.Path)
-			}
+// runShortestPathSearch runs a single path search and prints the path and
+// its recipe tree. The search stops after timeout, if positive, or on Ctrl+C.
+func runShortestPathSearch(label string, finder RecipeFinder, store *ElementStore, target string, timeout time.Duration) {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
 
-		case "3":
-			// Run Bidirectional search
-			fmt.Println("\nRunning Bidirectional search...")
-			startTime := time.Now()
-			bid := NewBidirectionalFinder(store)
-			bidResult, err := bid.FindShortestPath(target)
-			searchDuration := time.Since(startTime)
-
-			if err != nil {
-				fmt.Printf("Bidirectional Error: %v\n", err)
-			} else {
-				fmt.Printf("\nBidirectional search found a path with %d steps!\n", len(bidResult.Path))
-				fmt.Printf("Visited %d nodes during search\n", bidResult.VisitedNodes)
-				fmt.Printf("Algorithm execution time: %d ms\n", bidResult.ExecutionTime)
-				fmt.Printf("Total execution time: %v\n", searchDuration)
-
-				// Print recipe path
-				PrintRecipePath("Bidirectional", bidResult, store)
-
-				// Print recipe tree
-				PrintRecipeTree(store, target, bidResult.Path)
-			}
+	fmt.Printf("\nRunning %s search...\n", label)
+	startTime := time.Now()
+	result, err := finder.FindShortestPathContext(ctx, target)
+	searchDuration := time.Since(startTime)
 
-		default:
-			fmt.Println("Invalid choice. Please enter 1, 2, or 3.")
-			os.Exit(1)
-		}
-	} else {
-		// Multiple recipe paths search
-		switch algoChoice {
-		case "1":
-			// Run BFS search for multiple paths
-			fmt.Printf("\nRunning BFS search for up to %d recipe paths...\n", maxPaths)
-			startTime := time.Now()
-			bfs := NewBreadthFirstFinder(store)
-			bfsResults, err := bfs.FindMultiplePaths(target, maxPaths)
-			searchDuration := time.Since(startTime)
-
-			if err != nil {
-				fmt.Printf("BFS Error: %v\n", err)
-			} else {
-				fmt.Printf("\nBFS found %d different paths!\n", len(bfsResults))
-				fmt.Printf("Total execution time: %v\n", searchDuration)
-
-				// Print recipe paths
-				PrintMultipleRecipePaths("BFS", bfsResults, store)
-
-				// Print recipe tree for the first (shortest) path
-				if len(bfsResults) > 0 {
-					fmt.Println("\nTree visualization for the first path:")
-					PrintRecipeTree(store, target, bfsResults[0].Path)
-				}
-			}
+	if errors.Is(err, ErrSearchTimedOut) {
+		fmt.Printf("\n%s search timed out after %v without finding a path (visited %d nodes)\n",
+			label, searchDuration, result.VisitedNodes)
+		return
+	}
+	if err != nil {
+		fmt.Printf("%s Error: %v\n", label, err)
+		printNoPathDiagnostic(err)
+		return
+	}
 
-		case "2":
-			// Run DFS search for multiple paths
-			fmt.Printf("\nRunning DFS search for up to %d recipe paths...\n", maxPaths)
-			startTime := time.Now()
-			dfs := NewDepthFirstFinder(store)
-			dfsResults, err := dfs.FindMultiplePaths(target, maxPaths)
-			searchDuration := time.Since(startTime)
-
-			if err != nil {
-				fmt.Printf("DFS Error: %v\n", err)
-			} else {
-				fmt.Printf("\nDFS found %d different paths!\n", len(dfsResults))
-				fmt.Printf("Total execution time: %v\n", searchDuration)
-
-				// Print recipe paths
-				PrintMultipleRecipePaths("DFS", dfsResults, store)
-
-				// Print recipe tree for the first path
-				if len(dfsResults) > 0 {
-					fmt.Println("\nTree visualization for the first path:")
-					PrintRecipeTree(store, target, dfsResults[0].Path)
-				}
-			}
+	fmt.Printf("\n%s search found a path with %d steps!\n", label, len(result.Path))
+	fmt.Printf("Visited %d nodes during search\n", result.VisitedNodes)
+	fmt.Printf("Algorithm execution time: %d ms\n", result.ExecutionTime)
+	fmt.Printf("Total execution time: %v\n", searchDuration)
 
-		case "3":
-			// Run Bidirectional search for multiple paths
-			fmt.Printf("\nRunning Bidirectional search for up to %d recipe paths...\n", maxPaths)
-			startTime := time.Now()
-			bid := NewBidirectionalFinder(store)
-			bidResults, err := bid.FindMultiplePaths(target, maxPaths)
-			searchDuration := time.Since(startTime)
-
-			if err != nil {
-				fmt.Printf("Bidirectional Error: %v\n", err)
-			} else {
-				fmt.Printf("\nBidirectional search found %d different paths!\n", len(bidResults))
-				fmt.Printf("Total execution time: %v\n", searchDuration)
-
-				// Print recipe paths
-				PrintMultipleRecipePaths("Bidirectional", bidResults, store)
-
-				// Print recipe tree for the first path
-				if len(bidResults) > 0 {
-					fmt.Println("\nTree visualization for the first path:")
-					PrintRecipeTree(store, target, bidResults[0].Path)
-				}
-			}
+	// Print recipe path
+	PrintRecipePath(label, result, store)
 
-		default:
-			fmt.Println("Invalid choice. Please enter 1, 2, or 3.")
-			os.Exit(1)
+	// Print recipe tree
+	PrintRecipeTree(store, target, result.Path)
+}
+
+// runMultiplePathSearch runs a multiple path search, printing every path as
+// soon as it is found and then the recipe tree of the first. The search stops
+// after timeout, if positive, or on Ctrl+C, keeping the paths found by then.
+func runMultiplePathSearch(finder *RankedPathFinder, store *ElementStore, target string, maxPaths int, timeout time.Duration) {
+	ctx, cancel := searchContext(timeout)
+	defer cancel()
+
+	fmt.Printf("\nRunning ranked search for up to %d recipe paths...\n", maxPaths)
+	startTime := time.Now()
+	stream := finder.StreamMultiplePaths(ctx, target, maxPaths)
+
+	var results []*SearchResult
+	for update := range stream.Updates {
+		if update.Sequence == 1 {
+			fmt.Println("\nPaths as they are found, cheapest first:")
 		}
+		result := update.Result
+		fmt.Printf("\nPath %d (Length: %d, Cost: %g, Visited nodes: %d, found after %v):\n",
+			update.Sequence, len(result.Path), result.Cost, result.VisitedNodes, update.Elapsed.Round(time.Microsecond))
+		printRecipeSteps(result.Path, store)
+		results = append(results, result)
+	}
+	searchDuration := time.Since(startTime)
+	err := stream.Err()
+
+	if errors.Is(err, ErrSearchTimedOut) {
+		fmt.Printf("\nRanked search timed out after %v with %d of %d paths\n", searchDuration, len(results), maxPaths)
+		if len(results) == 0 {
+			return
+		}
+	} else if err != nil {
+		fmt.Printf("Ranked Search Error: %v\n", err)
+		printNoPathDiagnostic(err)
+		return
+	}
+
+	fmt.Printf("\nRanked search found %d different paths!\n", len(results))
+	fmt.Printf("Total execution time: %v\n", searchDuration)
+	if len(results) > 0 && results[0].SkippedRecipes > 0 {
+		fmt.Printf("Without tiers %d recipes whose ingredients are no cheaper than their result were left out, so trees using them are not listed\n",
+			results[0].SkippedRecipes)
+	}
+
+	// Print recipe tree for the first (cheapest) path
+	if len(results) > 0 {
+		fmt.Println("\nTree visualization for the first path:")
+		PrintRecipeTree(store, target, results[0].Path)
 	}
 }
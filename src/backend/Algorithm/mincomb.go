@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MinCombinationFinder finds the recipe plan with the fewest distinct
+// combinations. An intermediate element is only made once no matter how
+// often it is used, so the plan is a DAG rather than a tree.
+type MinCombinationFinder struct {
+	store         *ElementStore
+	maxIterations int
+}
+
+// combinationSearch holds the state of one branch and bound search
+type combinationSearch struct {
+	ctx        context.Context
+	recipes    map[string][]Recipe
+	available  map[string]bool   // Elements that need no combination
+	needed     map[string]bool   // Elements still to be made
+	made       map[string]Recipe // Elements made so far and their recipe
+	best       map[string]Recipe
+	iterations int
+	exhausted  bool
+}
+
+// MultiTargetResult is one plan that makes several targets, sharing the
+// intermediates they have in common
+type MultiTargetResult struct {
+	SearchResult // Path lists every combination once, ingredients first
+	Targets      []string
+	Trees        []*TreeNode // One tree per target, shared intermediates appear in each
+}
+
+// NewMinCombinationFinder creates finder instance
+func NewMinCombinationFinder(store *ElementStore) *MinCombinationFinder {
+	return &MinCombinationFinder{
+		store:         store,
+		maxIterations: 200000, // Cap on branch and bound nodes
+	}
+}
+
+// FindShortestPath finds the plan for target with the fewest combinations
+func (mf *MinCombinationFinder) FindShortestPath(target string) (*SearchResult, error) {
+	return mf.FindShortestPathContext(context.Background(), target)
+}
+
+// FindShortestPathContext finds the plan for target with the fewest
+// combinations, stopping once ctx is done
+func (mf *MinCombinationFinder) FindShortestPathContext(ctx context.Context, target string) (*SearchResult, error) {
+	return mf.FindFromInventoryContext(ctx, target, nil)
+}
+
+// FindFromInventory finds the fewest additional combinations that make target
+// when the discovered elements are already at hand. Basic elements are always
+// at hand, so an empty inventory is a search from scratch.
+func (mf *MinCombinationFinder) FindFromInventory(target string, discovered []string) (*SearchResult, error) {
+	return mf.FindFromInventoryContext(context.Background(), target, discovered)
+}
+
+// FindFromInventoryContext is FindFromInventory stopping once ctx is done. The
+// smallest full trees are a plan from the start, so a stopped search returns
+// the best plan found so far with ErrSearchTimedOut.
+func (mf *MinCombinationFinder) FindFromInventoryContext(ctx context.Context, target string, discovered []string) (*SearchResult, error) {
+	startTime := time.Now()
+
+	// Check target exists
+	if _, exists := mf.store.Elements[target]; !exists {
+		return nil, ErrElementNotFound
+	}
+
+	search, err := mf.searchTargets(ctx, []string{target}, discovered)
+	if err != nil {
+		return nil, err
+	}
+
+	path := planOrder([]string{target}, search.best, search.available)
+	if err := mf.store.VerifyPath(path, target, discovered...).Err(); err != nil {
+		return nil, err
+	}
+	tree := BuildRecipeTree(mf.store, target, path)
+	markAvailableLeaves(tree, search.available)
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	return &SearchResult{
+		Path:          path,
+		VisitedNodes:  search.iterations,
+		ExecutionTime: executionTime,
+		TreeStructure: buildTreeStructureFromNode(mf.store, tree),
+		Tree:          tree,
+		Limit:         mf.limit(search),
+		TimedOut:      ctx.Err() != nil,
+	}, searchStopped(ctx)
+}
+
+// FindForTargets finds one plan with the fewest combinations that makes every
+// target, starting from the discovered elements. An intermediate needed by
+// several targets is made once, so the plan is usually shorter than the
+// separate plans put together.
+func (mf *MinCombinationFinder) FindForTargets(targets []string, discovered []string) (*MultiTargetResult, error) {
+	return mf.FindForTargetsContext(context.Background(), targets, discovered)
+}
+
+// FindForTargetsContext is FindForTargets stopping once ctx is done, with the
+// best plan found so far and ErrSearchTimedOut
+func (mf *MinCombinationFinder) FindForTargetsContext(ctx context.Context, targets []string, discovered []string) (*MultiTargetResult, error) {
+	startTime := time.Now()
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets given")
+	}
+
+	// Each target is planned once however often it is listed
+	var unique []string
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		if _, exists := mf.store.Elements[target]; !exists {
+			return nil, fmt.Errorf("target %q: %w", target, ErrElementNotFound)
+		}
+		if !seen[target] {
+			seen[target] = true
+			unique = append(unique, target)
+		}
+	}
+
+	search, err := mf.searchTargets(ctx, unique, discovered)
+	if err != nil {
+		return nil, err
+	}
+
+	path := planOrder(unique, search.best, search.available)
+	for _, target := range unique {
+		if err := mf.store.VerifyPath(path, target, discovered...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	trees := make([]*TreeNode, 0, len(unique))
+	structures := make([]interface{}, 0, len(unique))
+	for _, target := range unique {
+		tree := BuildRecipeTree(mf.store, target, planOrder([]string{target}, search.best, search.available))
+		markAvailableLeaves(tree, search.available)
+		trees = append(trees, tree)
+		structures = append(structures, buildTreeStructureFromNode(mf.store, tree))
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	return &MultiTargetResult{
+		SearchResult: SearchResult{
+			Path:          path,
+			VisitedNodes:  search.iterations,
+			ExecutionTime: executionTime,
+			TreeStructure: structures,
+			Limit:         mf.limit(search),
+			TimedOut:      ctx.Err() != nil,
+		},
+		Targets: unique,
+		Trees:   trees,
+	}, searchStopped(ctx)
+}
+
+// searchTargets runs the branch and bound search for a plan that makes every
+// target from the basic and discovered elements
+func (mf *MinCombinationFinder) searchTargets(ctx context.Context, targets []string, discovered []string) (*combinationSearch, error) {
+	if len(mf.store.BasicElements) == 0 {
+		return nil, ErrNoBasicElements
+	}
+
+	// Everything at hand is a leaf of the plan
+	leaves := append([]string{}, mf.store.BasicElements...)
+	for _, element := range discovered {
+		if _, exists := mf.store.Elements[element]; !exists {
+			return nil, fmt.Errorf("discovered element %q: %w", element, ErrElementNotFound)
+		}
+		leaves = append(leaves, element)
+	}
+
+	// The smallest full trees give the initial upper bound
+	costs := NewRecipeTreeFinder(mf.store).solveFrom(leaves)
+	for _, target := range targets {
+		if _, found := costs[target]; !found {
+			return nil, mf.store.explainNoPath(target, nil, "")
+		}
+	}
+
+	search := &combinationSearch{
+		ctx:       ctx,
+		recipes:   mf.orderedRecipes(costs),
+		available: make(map[string]bool),
+		needed:    make(map[string]bool),
+		made:      make(map[string]Recipe),
+		best:      make(map[string]Recipe),
+	}
+	for _, leaf := range leaves {
+		search.available[leaf] = true
+	}
+	for element, choice := range costs {
+		if !choice.Basic {
+			search.best[element] = choice.Recipe
+		}
+	}
+	search.best = mf.reachable(targets, search.best, search.available)
+
+	for _, target := range targets {
+		if !search.available[target] {
+			search.needed[target] = true
+		}
+	}
+	if len(search.needed) > 0 {
+		mf.branch(search)
+	}
+
+	return search, nil
+}
+
+// limit names the limit that stopped the search, or is empty when the search
+// ran to the end. The smallest full trees are always a plan, so running out
+// of iterations leaves a plan that may not be minimal rather than no plan.
+func (mf *MinCombinationFinder) limit(search *combinationSearch) string {
+	if search.iterations <= mf.maxIterations {
+		return ""
+	}
+	return fmt.Sprintf("iteration limit of %d", mf.maxIterations)
+}
+
+// reachable keeps only the chosen recipes that the targets actually need
+func (mf *MinCombinationFinder) reachable(targets []string, chosen map[string]Recipe, available map[string]bool) map[string]Recipe {
+	needed := make(map[string]Recipe)
+	for _, recipe := range planOrder(targets, chosen, available) {
+		needed[recipe.Result] = recipe
+	}
+	return needed
+}
+
+// markAvailableLeaves turns elements already at hand into leaves, so they are
+// not listed as combinations of the tree
+func markAvailableLeaves(node *TreeNode, available map[string]bool) {
+	if available[node.Element] && len(node.Children) == 0 {
+		node.IsResult = false
+	}
+	for _, child := range node.Children {
+		markAvailableLeaves(child, available)
+	}
+}
+
+// orderedRecipes lists the recipes of every element, trying the ones that
+// come from the smallest trees first so good plans are found early
+func (mf *MinCombinationFinder) orderedRecipes(costs map[string]treeChoice) map[string][]Recipe {
+	recipes := mf.store.RecipesByResult()
+
+	for _, list := range recipes {
+		sort.SliceStable(list, func(i, j int) bool {
+			return recipeTreeCost(list[i], costs) < recipeTreeCost(list[j], costs)
+		})
+	}
+
+	return recipes
+}
+
+// recipeTreeCost returns the smallest tree size of a recipe, or the largest
+// int if one of its ingredients cannot be made
+func recipeTreeCost(recipe Recipe, costs map[string]treeChoice) int {
+	cost := 1
+	for _, ingredient := range recipe.Ingredients {
+		choice, exists := costs[ingredient]
+		if !exists {
+			return int(^uint(0) >> 1)
+		}
+		cost += choice.Cost
+	}
+	return cost
+}
+
+// branch resolves the highest tier element still needed with each of its
+// recipes in turn. With tiers every ingredient is of a lower tier than its
+// result, so an element is never needed again once it has been resolved.
+// Without tiers a recipe can need its own result, directly or through the
+// elements already resolved on the way to it, and such recipes are skipped.
+func (mf *MinCombinationFinder) branch(search *combinationSearch) {
+	// Every needed element costs at least one more combination
+	if len(search.made)+len(search.needed) >= len(search.best) {
+		return
+	}
+
+	if len(search.needed) == 0 {
+		search.best = make(map[string]Recipe, len(search.made))
+		for element, recipe := range search.made {
+			search.best[element] = recipe
+		}
+		return
+	}
+
+	search.iterations++
+	if search.iterations > mf.maxIterations || search.ctx.Err() != nil {
+		search.exhausted = true
+		return
+	}
+
+	element := mf.nextNeeded(search.needed)
+	delete(search.needed, element)
+
+	for _, recipe := range search.recipes[element] {
+		if mf.store.TiersIgnored() && mf.cyclic(search, element, recipe) {
+			continue
+		}
+
+		// Queue the ingredients that are not available yet
+		var added []string
+		for _, ingredient := range recipe.Ingredients {
+			if search.available[ingredient] || search.needed[ingredient] {
+				continue
+			}
+			if _, done := search.made[ingredient]; done {
+				continue
+			}
+			search.needed[ingredient] = true
+			added = append(added, ingredient)
+		}
+
+		search.made[element] = recipe
+		mf.branch(search)
+		delete(search.made, element)
+
+		for _, ingredient := range added {
+			delete(search.needed, ingredient)
+		}
+
+		if search.exhausted {
+			break
+		}
+	}
+
+	search.needed[element] = true
+}
+
+// cyclic reports whether making element with recipe needs element again: an
+// ingredient is element itself or was resolved with a recipe that needs it
+func (mf *MinCombinationFinder) cyclic(search *combinationSearch, element string, recipe Recipe) bool {
+	for _, ingredient := range recipe.Ingredients {
+		if search.available[ingredient] {
+			continue
+		}
+		if ingredient == element || planNeeds(search.made, ingredient, element) {
+			return true
+		}
+	}
+	return false
+}
+
+// planNeeds reports whether the recipe chosen for from needs element,
+// directly or through other chosen recipes
+func planNeeds(chosen map[string]Recipe, from, element string) bool {
+	seen := make(map[string]bool)
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == element {
+			return true
+		}
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+
+		if recipe, exists := chosen[current]; exists {
+			queue = append(queue, recipe.Ingredients...)
+		}
+	}
+	return false
+}
+
+// nextNeeded picks the needed element with the highest tier
+func (mf *MinCombinationFinder) nextNeeded(needed map[string]bool) string {
+	next := ""
+	nextTier := -1
+	for element := range needed {
+		tier := mf.store.GetElementTier(element)
+		if tier > nextTier || (tier == nextTier && element < next) {
+			next = element
+			nextTier = tier
+		}
+	}
+	return next
+}
+
+// planOrder lists the chosen recipes so that ingredients are made before the
+// elements that need them, starting from the given targets
+func planOrder(targets []string, chosen map[string]Recipe, available map[string]bool) []Recipe {
+	var path []Recipe
+	done := make(map[string]bool)
+
+	var visit func(element string)
+	visit = func(element string) {
+		if done[element] || available[element] {
+			return
+		}
+		done[element] = true
+
+		recipe, exists := chosen[element]
+		if !exists {
+			return
+		}
+		for _, ingredient := range recipe.Ingredients {
+			visit(ingredient)
+		}
+		path = append(path, recipe)
+	}
+
+	for _, target := range targets {
+		visit(target)
+	}
+	return path
+}
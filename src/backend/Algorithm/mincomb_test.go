@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMinCombinationFinder(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		discovered []string
+		want       int // Combinations in the plan
+		err        error
+	}{
+		{name: "basic element", target: "Fire", want: 0},
+		{name: "single tree", target: "Brick", want: 3},
+		{name: "ingredient made once", target: "Pebble", want: 3},
+		{name: "intermediate shared across branches", target: "Wall", want: 4},
+		{name: "discovered ingredient", target: "Wall", discovered: []string{"Stone"}, want: 3},
+		{name: "already discovered", target: "Brick", discovered: []string{"Brick"}, want: 0},
+		{name: "no valid recipe", target: "Rain", err: ErrNoPathFound},
+		{name: "unknown discovered element", target: "Brick", discovered: []string{"Gold"}, err: ErrElementNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+
+			result, err := NewMinCombinationFinder(store).FindFromInventory(tt.target, tt.discovered)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("FindFromInventory(%q) error = %v, want %v", tt.target, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindFromInventory(%q) error = %v", tt.target, err)
+			}
+			if len(result.Path) != tt.want {
+				t.Errorf("FindFromInventory(%q) made %d combinations, want %d: %v", tt.target, len(result.Path), tt.want, result.Path)
+			}
+			if result.Limit != "" {
+				t.Errorf("FindFromInventory(%q) stopped at the %s", tt.target, result.Limit)
+			}
+			if err := store.VerifyPath(result.Path, tt.target, tt.discovered...).Err(); err != nil {
+				t.Errorf("plan is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestMinCombinationFinderOptimal(t *testing.T) {
+	store := loadTestStore(t)
+	finder := NewMinCombinationFinder(store)
+
+	// Every plan unfolds into a tree, so the fewest distinct combinations of
+	// any tree is the smallest plan there is
+	for target := range store.Elements {
+		trees := NewTreeEnumerator(store, StepsCost{}, nil).Top(context.Background(), target, 100)
+		if len(trees) == 0 {
+			continue
+		}
+		fewest := len(RecipeTreeOrder(trees[0]))
+		for _, tree := range trees[1:] {
+			if combinations := len(RecipeTreeOrder(tree)); combinations < fewest {
+				fewest = combinations
+			}
+		}
+
+		result, err := finder.FindShortestPath(target)
+		if err != nil {
+			t.Fatalf("FindShortestPath(%q) error = %v", target, err)
+		}
+		if len(result.Path) != fewest {
+			t.Errorf("FindShortestPath(%q) made %d combinations, want %d", target, len(result.Path), fewest)
+		}
+	}
+}
+
+func TestMinCombinationFinderTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+		want    int
+	}{
+		{name: "one target", targets: []string{"Brick"}, want: 3},
+		{name: "shared intermediate made once", targets: []string{"Brick", "Pebble"}, want: 4},
+		{name: "repeated target", targets: []string{"Pebble", "Pebble"}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+
+			result, err := NewMinCombinationFinder(store).FindForTargets(tt.targets, nil)
+			if err != nil {
+				t.Fatalf("FindForTargets(%v) error = %v", tt.targets, err)
+			}
+			if len(result.Path) != tt.want {
+				t.Errorf("FindForTargets(%v) made %d combinations, want %d: %v", tt.targets, len(result.Path), tt.want, result.Path)
+			}
+		})
+	}
+}
+
+func TestMinCombinationFinderTargetsShareSubtrees(t *testing.T) {
+	// Both pairs share a subtree: Brick and Pebble need Stone, and Wall is
+	// made from Brick. One plan makes it once, so it is shorter than the
+	// plans for each target on their own.
+	for _, targets := range [][]string{{"Brick", "Pebble"}, {"Wall", "Brick"}} {
+		store := loadTestStore(t)
+		finder := NewMinCombinationFinder(store)
+
+		separate := 0
+		for _, target := range targets {
+			result, err := finder.FindShortestPath(target)
+			if err != nil {
+				t.Fatalf("FindShortestPath(%q) error = %v", target, err)
+			}
+			separate += len(result.Path)
+		}
+
+		result, err := finder.FindForTargets(targets, nil)
+		if err != nil {
+			t.Fatalf("FindForTargets(%v) error = %v", targets, err)
+		}
+		if len(result.Path) >= separate {
+			t.Errorf("FindForTargets(%v) made %d combinations, want fewer than the %d of separate plans", targets, len(result.Path), separate)
+		}
+
+		made := make(map[string]bool)
+		for _, recipe := range result.Path {
+			made[recipe.Result] = true
+		}
+		for _, target := range targets {
+			if !made[target] {
+				t.Errorf("FindForTargets(%v) never makes %s: %v", targets, target, result.Path)
+			}
+		}
+	}
+}
+
+func TestMinCombinationFinderWithoutTiers(t *testing.T) {
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{target: "Mud", want: 1},   // Its Stone recipe needs Mud again
+		{target: "Stone", want: 2}, // Its Mud recipe needs Stone again
+		{target: "Rain", want: 3},  // Dropped with tiers
+		{target: "Wall", want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			store := loadTestStore(t)
+			store.IgnoreTiers()
+
+			result, err := NewMinCombinationFinder(store).FindShortestPath(tt.target)
+			if err != nil {
+				t.Fatalf("FindShortestPath(%q) error = %v", tt.target, err)
+			}
+			if len(result.Path) != tt.want {
+				t.Errorf("FindShortestPath(%q) made %d combinations, want %d: %v", tt.target, len(result.Path), tt.want, result.Path)
+			}
+			if err := store.VerifyPath(result.Path, tt.target).Err(); err != nil {
+				t.Errorf("plan is invalid: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrPackNotFound is returned when an owned pack is neither a pack the
+// scraper knows nor one that appears in the element data
+var ErrPackNotFound = errors.New("pack not found")
+
+// expansionPacks lists the packs the scraper tags, as in Scraper/scraper.go.
+// They can be owned even when the element data holds none of their content.
+var expansionPacks = []string{"Myths and Monsters"}
+
+// assignPacks works out which expansion pack each scraped recipe needs: the
+// pack the wiki marks it with, else the pack of its result or of one of its
+// ingredients
+func (es *ElementStore) assignPacks() {
+	for i, recipe := range es.scraped {
+		es.scraped[i].Pack = es.recipePack(recipe)
+	}
+}
+
+// recipePack returns the expansion pack recipe needs, or an empty string for
+// base-game content
+func (es *ElementStore) recipePack(recipe Recipe) string {
+	result, exists := es.Elements[recipe.Result]
+	if !exists {
+		return ""
+	}
+	// Check packs in a fixed order so a recipe listed under two packs always
+	// gets the same one
+	packs := make([]string, 0, len(result.PackRecipes))
+	for pack := range result.PackRecipes {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+	for _, pack := range packs {
+		for _, ingredients := range result.PackRecipes[pack] {
+			if sameIngredients(ingredients, recipe.Ingredients) {
+				return pack
+			}
+		}
+	}
+
+	if result.Pack != "" {
+		return result.Pack
+	}
+	for _, ingredient := range recipe.Ingredients {
+		if elem, exists := es.Elements[ingredient]; exists && elem.Pack != "" {
+			return elem.Pack
+		}
+	}
+	return ""
+}
+
+// Packs lists the expansion packs that appear in the element data
+func (es *ElementStore) Packs() []string {
+	seen := make(map[string]bool)
+	for _, recipe := range es.scraped {
+		if recipe.Pack != "" {
+			seen[recipe.Pack] = true
+		}
+	}
+	for _, elem := range es.Elements {
+		if elem.Pack != "" {
+			seen[elem.Pack] = true
+		}
+	}
+
+	packs := make([]string, 0, len(seen))
+	for pack := range seen {
+		packs = append(packs, pack)
+	}
+	sort.Strings(packs)
+	return packs
+}
+
+// KnownPacks lists the packs that can be owned: the packs the scraper tags
+// and any others that appear in the element data
+func (es *ElementStore) KnownPacks() []string {
+	packs := es.Packs()
+	seen := make(map[string]bool, len(packs))
+	for _, pack := range packs {
+		seen[pack] = true
+	}
+	for _, pack := range expansionPacks {
+		if !seen[pack] {
+			packs = append(packs, pack)
+		}
+	}
+	sort.Strings(packs)
+	return packs
+}
+
+// RestrictToPacks limits searches to base-game content and the owned
+// expansion packs. Recipes and basic elements from any other pack are
+// dropped, so an empty list leaves only the base game.
+func (es *ElementStore) RestrictToPacks(owned []string) error {
+	known := make(map[string]bool)
+	for _, pack := range es.KnownPacks() {
+		known[pack] = true
+	}
+
+	es.ownedPacks = make(map[string]bool, len(owned))
+	for _, pack := range owned {
+		if !known[pack] {
+			return fmt.Errorf("pack %q: %w", pack, ErrPackNotFound)
+		}
+		es.ownedPacks[pack] = true
+	}
+
+	es.BasicElements = es.BasicElements[:0]
+	es.Locked = nil
+	for _, element := range es.basics {
+		if es.elementOwned(element) {
+			es.BasicElements = append(es.BasicElements, element)
+		}
+	}
+	es.filterRecipes()
+	es.resolveUnlocks()
+	return nil
+}
+
+// packOwned reports whether content from pack may be searched. Base-game
+// content, with an empty pack, always may.
+func (es *ElementStore) packOwned(pack string) bool {
+	return pack == "" || es.ownedPacks == nil || es.ownedPacks[pack]
+}
+
+// elementOwned reports whether element belongs to the base game or an owned pack
+func (es *ElementStore) elementOwned(element string) bool {
+	elem, exists := es.Elements[element]
+	return !exists || es.packOwned(elem.Pack)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRestrictToPacks(t *testing.T) {
+	tests := []struct {
+		name    string
+		packs   []string // Owned packs, nil for all of them
+		phoenix bool     // Whether Phoenix can be made
+		ember   int      // Combinations in the smallest Ember tree
+	}{
+		{name: "every pack", packs: nil, phoenix: true, ember: 3},
+		{name: "owned pack", packs: []string{"Myths and Monsters"}, phoenix: true, ember: 3},
+		{name: "base game", packs: []string{}, phoenix: false, ember: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+			if tt.packs != nil {
+				if err := store.RestrictToPacks(tt.packs); err != nil {
+					t.Fatalf("RestrictToPacks(%v) error = %v", tt.packs, err)
+				}
+			}
+
+			// Every finder must agree on whether the pack element can be made
+			for _, info := range Finders() {
+				_, err := info.New(store).FindShortestPath("Phoenix")
+				if tt.phoenix && err != nil {
+					t.Errorf("%s: FindShortestPath(Phoenix) error = %v", info.Name, err)
+				}
+				if !tt.phoenix && !errors.Is(err, ErrNoPathFound) {
+					t.Errorf("%s: FindShortestPath(Phoenix) error = %v, want %v", info.Name, err, ErrNoPathFound)
+				}
+			}
+
+			// Ember's pack recipe through Phoenix is smaller than its base-game one
+			result, err := NewRecipeTreeFinder(store).FindShortestPath("Ember")
+			if err != nil {
+				t.Fatalf("FindShortestPath(Ember) error = %v", err)
+			}
+			if got := countTreeCombinations(result.Tree); got != tt.ember {
+				t.Errorf("Ember tree has %d combinations, want %d", got, tt.ember)
+			}
+			if err := store.VerifyTree(result.Tree).Err(); err != nil {
+				t.Errorf("Ember tree is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestrictToUnknownPack(t *testing.T) {
+	store := loadTestStore(t)
+
+	if err := store.RestrictToPacks([]string{"Gold Rush"}); !errors.Is(err, ErrPackNotFound) {
+		t.Errorf("RestrictToPacks(Gold Rush) error = %v, want %v", err, ErrPackNotFound)
+	}
+}
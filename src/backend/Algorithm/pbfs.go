@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+// frontierEntry is a search state reached by a parallel search, with what
+// orders it the way a sequential search would first reach it: its level,
+// then the position of its parent in that level, then the position of the
+// recipe among the parent's expansions
+type frontierEntry struct {
+	state  searchState
+	key    string
+	step   RecipeStep
+	cost   float64 // Cost of the chain that reached the state this way
+	level  int
+	parent int
+	recipe int
+}
+
+// before reports whether a sequential search reaches e before other
+func (e frontierEntry) before(other frontierEntry) bool {
+	if e.level != other.level {
+		return e.level < other.level
+	}
+	if e.parent != other.parent {
+		return e.parent < other.parent
+	}
+	return e.recipe < other.recipe
+}
+
+// sameEntry reports whether e and other reach their state the same way
+func (e frontierEntry) sameEntry(other frontierEntry) bool {
+	return e.level == other.level && e.parent == other.parent && e.recipe == other.recipe
+}
+
+// cheaper reports whether a sequential search keeps e over other as the
+// parent of their state: e costs less, or as much and comes first
+func (e frontierEntry) cheaper(other frontierEntry) bool {
+	if e.cost != other.cost {
+		return e.cost < other.cost
+	}
+	return e.before(other)
+}
+
+// visitedState is how a parallel search reached one state: the entry that
+// reached it first, which places it in its level, and the cheapest entry,
+// whose step is the state's parent
+type visitedState struct {
+	first    frontierEntry
+	cheapest frontierEntry
+}
+
+// minEntriesPerWorker is the fewest frontier entries worth handing to a
+// worker. Expanding an entry takes far less than starting a goroutine, so
+// smaller levels are expanded on the search's own goroutine.
+const minEntriesPerWorker = 64
+
+// visitedShards is how many separately locked parts a visitedSet has
+const visitedShards = 64
+
+// visitedShard is one locked part of a visitedSet
+type visitedShard struct {
+	mu      sync.Mutex
+	entries map[string]visitedState
+}
+
+// visitedSet holds the states a parallel search has reached and is safe for
+// concurrent use. Each state keeps the entries a sequential search would have
+// first reached it by and chosen as its parent, so neither depends on
+// scheduling.
+type visitedSet struct {
+	shards [visitedShards]visitedShard
+}
+
+// newVisitedSet creates an empty set
+func newVisitedSet() *visitedSet {
+	set := &visitedSet{}
+	for i := range set.shards {
+		set.shards[i].entries = make(map[string]visitedState)
+	}
+	return set
+}
+
+// shard returns the part of the set holding key
+func (s *visitedSet) shard(key string) *visitedShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return &s.shards[hash.Sum32()%visitedShards]
+}
+
+// offer records entry as the first way to its state unless an entry that
+// comes before it already reached the state, reporting whether it was, and
+// as the state's parent when it is the cheapest way of its level
+func (s *visitedSet) offer(entry frontierEntry) bool {
+	shard := s.shard(entry.key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	known, seen := shard.entries[entry.key]
+	if !seen {
+		shard.entries[entry.key] = visitedState{first: entry, cheapest: entry}
+		return true
+	}
+	if known.first.level != entry.level {
+		return false // Reached on an earlier level
+	}
+
+	first := entry.before(known.first)
+	if first {
+		known.first = entry
+	}
+	if entry.cheaper(known.cheapest) {
+		known.cheapest = entry
+	}
+	shard.entries[entry.key] = known
+	return first
+}
+
+// get returns how a state was reached
+func (s *visitedSet) get(key string) (visitedState, bool) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	entry, seen := shard.entries[key]
+	return entry, seen
+}
+
+// len returns how many states have been reached
+func (s *visitedSet) len() int {
+	count := 0
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		count += len(s.shards[i].entries)
+		s.shards[i].mu.Unlock()
+	}
+	return count
+}
+
+// parents returns the recipe step that reached each state after the first level
+func (s *visitedSet) parents() map[string]RecipeStep {
+	parents := make(map[string]RecipeStep)
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		for key, reached := range s.shards[i].entries {
+			if reached.cheapest.level > 0 {
+				parents[key] = reached.cheapest.step
+			}
+		}
+		s.shards[i].mu.Unlock()
+	}
+	return parents
+}
+
+// findShortestPathParallel searches level by level, splitting each frontier
+// level large enough across the search's workers. Workers record the states
+// they reach in a shared visited set that keeps, for every state, the parent a
+// sequential search would have chosen, and the next level is put in
+// sequential order, so the path found is the same as the sequential search's.
+func (bf *BreadthFirstFinder) findShortestPathParallel(ctx context.Context, target string, targetTier int, required *requirements, basicElements []*Element, startTime time.Time) (*SearchResult, error) {
+	visited := newVisitedSet()
+	goal := required.goalKey(target)
+	chains := newChainCost(bf.store, bf.costModel)
+
+	// The first level holds the basic elements
+	var frontier []frontierEntry
+	for i, elem := range basicElements {
+		start := searchState{Element: elem.ID, Mask: required.add(0, elem.ID)}
+		entry := frontierEntry{state: start, key: required.key(start), cost: chains.start(elem.ID), parent: i}
+		if visited.offer(entry) {
+			frontier = append(frontier, entry)
+		}
+	}
+
+	workers := bf.workers.size()
+	used := 0 // Most workers any level was expanded on
+	for level := 1; len(frontier) > 0; level++ {
+		if _, found := visited.get(goal); found {
+			break
+		}
+
+		// Each worker expands one contiguous part of the level, of at least
+		// minEntriesPerWorker entries
+		chunks := len(frontier) / minEntriesPerWorker
+		if chunks > workers {
+			chunks = workers
+		}
+		if chunks < 1 {
+			chunks = 1
+		}
+		if chunks > used {
+			used = chunks
+		}
+		reached := make([][]frontierEntry, chunks)
+		expand := func(chunk int) {
+			end := (chunk + 1) * len(frontier) / chunks
+			for index := chunk * len(frontier) / chunks; index < end && ctx.Err() == nil; index++ {
+				reached[chunk] = bf.expandEntry(frontier[index], index, level, targetTier, required, chains, visited, reached[chunk])
+			}
+		}
+		if chunks == 1 {
+			expand(0)
+		} else {
+			bf.workers.run(ctx, chunks, expand)
+		}
+
+		// A partly expanded level may have chosen different parents
+		if err := searchStopped(ctx); err != nil {
+			return timedOutResult(visited.len(), startTime), err
+		}
+
+		// The next level holds the entries no earlier one displaced, each
+		// continuing the cheapest chain to its state
+		var next []frontierEntry
+		for _, entries := range reached {
+			for _, entry := range entries {
+				if kept, _ := visited.get(entry.key); kept.first.sameEntry(entry) {
+					entry.cost = kept.cheapest.cost
+					next = append(next, entry)
+				}
+			}
+		}
+		sort.Slice(next, func(i, j int) bool {
+			return next[i].before(next[j])
+		})
+		frontier = next
+	}
+
+	goalState, found := visited.get(goal)
+	if !found {
+		return nil, bf.store.explainNoPath(target, bf.constraints, "")
+	}
+
+	// Like a sequential search, the whole level the goal was reached from has
+	// been expanded. That search takes a basic element target off its queue
+	// only after expanding the basic elements before it.
+	if goalState.first.level == 0 {
+		var reached []frontierEntry
+		for index := 0; index < goalState.first.parent; index++ {
+			reached = bf.expandEntry(frontier[index], index, 1, targetTier, required, chains, visited, reached)
+		}
+	}
+	visitedCount := visited.len()
+
+	path := bf.reconstructPath(goal, visited.parents())
+	result, err := bf.pathResult(path, target, visitedCount, startTime)
+	if err != nil {
+		return nil, err
+	}
+	result.Workers = used
+	return result, nil
+}
+
+// expandEntry offers every state reachable from one frontier entry to the
+// visited set, appending those recorded to reached
+func (bf *BreadthFirstFinder) expandEntry(from frontierEntry, index, level, targetTier int, required *requirements, chains chainCost, visited *visitedSet, reached []frontierEntry) []frontierEntry {
+	currentTier := bf.store.GetElementTier(from.state.Element)
+	for order, recipe := range bf.getPossibleRecipesThatRespectTiers(from.state.Element, currentTier, targetTier) {
+		next := searchState{Element: recipe.Result, Mask: required.addRecipe(from.state.Mask, recipe)}
+		entry := frontierEntry{
+			state:  next,
+			key:    required.key(next),
+			step:   RecipeStep{ParentID: from.key, Recipe: recipe},
+			cost:   chains.step(from.cost, from.state.Element, recipe),
+			level:  level,
+			parent: index,
+			recipe: order,
+		}
+		if visited.offer(entry) {
+			reached = append(reached, entry)
+		}
+	}
+	return reached
+}
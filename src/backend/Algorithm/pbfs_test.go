@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// scrapedElements is the full element data, large enough for parallel levels
+// to be split across workers
+const scrapedElements = "../Scraper/elements.json"
+
+// targetStride searches every targetStride-th element by name, which keeps
+// every tier covered while the test stays quick under the race detector
+const targetStride = 10
+
+func TestParallelBFSMatchesSequential(t *testing.T) {
+	if _, err := os.Stat(scrapedElements); err != nil {
+		t.Skipf("scraped elements not available: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		ignoreTiers bool
+		constraints *SearchConstraints
+	}{
+		{name: "tiers"},
+		{name: "without tiers", ignoreTiers: true},
+		{name: "required element", constraints: &SearchConstraints{RequireElements: []string{"Mud"}}},
+		{name: "excluded element", constraints: &SearchConstraints{ExcludeElements: []string{"Fire"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewElementStore(scrapedElements)
+			if err != nil {
+				t.Fatalf("loading scraped elements: %v", err)
+			}
+			if tt.ignoreTiers {
+				store.IgnoreTiers()
+			}
+
+			sequential := NewBreadthFirstFinder(store)
+			sequential.SetConstraints(tt.constraints)
+			parallel := NewBreadthFirstFinder(store)
+			parallel.SetConstraints(tt.constraints)
+			parallel.SetParallel(true)
+			parallel.SetWorkers(NewWorkerPool(4), 0)
+
+			targets := make([]string, 0, len(store.Elements))
+			for element := range store.Elements {
+				targets = append(targets, element)
+			}
+			sort.Strings(targets)
+
+			mostWorkers := 0
+			for i := 0; i < len(targets); i += targetStride {
+				target := targets[i]
+				want, wantErr := sequential.FindShortestPath(target)
+				got, gotErr := parallel.FindShortestPath(target)
+
+				if wantErr != nil || gotErr != nil {
+					if !errors.Is(gotErr, ErrNoPathFound) || !errors.Is(wantErr, ErrNoPathFound) {
+						t.Errorf("%s: parallel error = %v, sequential error = %v", target, gotErr, wantErr)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(got.Path, want.Path) {
+					t.Errorf("%s: parallel path = %v, sequential path = %v", target, got.Path, want.Path)
+				}
+				if got.VisitedNodes != want.VisitedNodes {
+					t.Errorf("%s: parallel visited %d nodes, sequential visited %d", target, got.VisitedNodes, want.VisitedNodes)
+				}
+				if got.Workers > mostWorkers {
+					mostWorkers = got.Workers
+				}
+			}
+
+			if mostWorkers < 2 {
+				t.Errorf("no search was split across workers, most used %d", mostWorkers)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ReachabilityReport explains which elements the searches can never make
+type ReachabilityReport struct {
+	Elements    int             `json:"elements"`
+	Reachable   int             `json:"reachable"`
+	Unreachable []string        `json:"unreachable"` // Not derivable from the basic elements
+	NoRecipes   []string        `json:"noRecipes"`   // Non-basic elements without a valid recipe
+	Locked      []string        `json:"locked"`      // Special elements whose unlock condition cannot be met
+	Unowned     []string        `json:"unowned"`     // Elements from expansion packs left out of the search
+	Packs       []string        `json:"packs"`       // Expansion packs tagged in the element data, empty when it has no pack tags
+	Dropped     []DroppedRecipe `json:"droppedRecipes"`
+}
+
+// DroppedRecipe is a scraped recipe rejected while loading and the reason
+type DroppedRecipe struct {
+	Ingredients []string `json:"ingredients"`
+	Result      string   `json:"result"`
+	Reason      string   `json:"reason"`
+}
+
+// Reachability computes the closure of the basic elements over the valid
+// recipes and reports every element outside it, the elements left without
+// a recipe and the recipes dropped while loading
+func (es *ElementStore) Reachability() *ReachabilityReport {
+	reachable := NewRecipeTreeFinder(es).solve()
+
+	hasRecipe := make(map[string]bool)
+	for _, recipe := range es.Recipes {
+		hasRecipe[recipe.Result] = true
+	}
+
+	report := &ReachabilityReport{
+		Elements:    len(es.Elements),
+		Reachable:   len(reachable),
+		Unreachable: []string{},
+		NoRecipes:   []string{},
+		Locked:      append([]string{}, es.Locked...),
+		Unowned:     []string{},
+		Packs:       es.Packs(),
+		Dropped:     []DroppedRecipe{},
+	}
+	for element := range es.Elements {
+		if !es.elementOwned(element) {
+			report.Unowned = append(report.Unowned, element)
+			continue
+		}
+		if _, ok := reachable[element]; !ok {
+			report.Unreachable = append(report.Unreachable, element)
+		}
+		if !es.IsBasicElement(element) && !hasRecipe[element] && es.UnlockCondition(element) == nil {
+			report.NoRecipes = append(report.NoRecipes, element)
+		}
+	}
+	es.sortByTier(report.Unreachable)
+	es.sortByTier(report.NoRecipes)
+	es.sortByTier(report.Unowned)
+
+	for _, recipe := range es.Dropped {
+		report.Dropped = append(report.Dropped, DroppedRecipe{
+			Ingredients: recipe.Ingredients,
+			Result:      recipe.Result,
+			Reason:      es.dropReason(recipe),
+		})
+	}
+
+	return report
+}
+
+// dropReason explains why the loader rejected recipe
+func (es *ElementStore) dropReason(recipe Recipe) string {
+	if len(recipe.Ingredients) != 2 {
+		return fmt.Sprintf("has %d ingredients, expected 2", len(recipe.Ingredients))
+	}
+	if !es.packOwned(recipe.Pack) {
+		return fmt.Sprintf("needs the %s pack", recipe.Pack)
+	}
+
+	resultTier := es.GetElementTier(recipe.Result)
+	for _, ingredient := range recipe.Ingredients {
+		if _, exists := es.Elements[ingredient]; !exists {
+			return fmt.Sprintf("unknown ingredient %q", ingredient)
+		}
+		if tier := es.GetElementTier(ingredient); tier >= resultTier {
+			return fmt.Sprintf("ingredient %q is tier %d, not below result tier %d", ingredient, tier, resultTier)
+		}
+	}
+	return "unknown"
+}
+
+// sortByTier orders elements by tier, then by name
+func (es *ElementStore) sortByTier(elements []string) {
+	sort.Slice(elements, func(i, j int) bool {
+		if tierI, tierJ := es.GetElementTier(elements[i]), es.GetElementTier(elements[j]); tierI != tierJ {
+			return tierI < tierJ
+		}
+		return elements[i] < elements[j]
+	})
+}
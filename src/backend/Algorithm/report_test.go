@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReachability(t *testing.T) {
+	tests := []struct {
+		name        string
+		ignoreTiers bool
+		packs       []string // Owned packs, nil for all of them
+		unreachable []string
+		noRecipes   []string
+		unowned     []string
+		dropped     map[string]string // Reason each result lost a recipe
+	}{
+		{
+			name:        "tiers",
+			unreachable: []string{"Ghost", "Rain", "Spirit"},
+			noRecipes:   []string{"Ghost", "Rain", "Spirit"},
+			unowned:     []string{},
+			dropped: map[string]string{
+				"Mud":    `ingredient "Stone" is tier 2, not below result tier 1`,
+				"Rain":   `ingredient "Cloud" is tier 2, not below result tier 1`,
+				"Ghost":  `ingredient "Spirit" is tier 1, not below result tier 1`,
+				"Spirit": `ingredient "Ghost" is tier 1, not below result tier 1`,
+			},
+		},
+		{
+			// Ghost and Spirit keep their recipes but only make each other
+			name:        "without tiers",
+			ignoreTiers: true,
+			unreachable: []string{"Ghost", "Spirit"},
+			noRecipes:   []string{},
+			unowned:     []string{},
+			dropped:     map[string]string{},
+		},
+		{
+			name:        "base game",
+			packs:       []string{},
+			unreachable: []string{"Ghost", "Rain", "Spirit"},
+			noRecipes:   []string{"Ghost", "Rain", "Spirit"},
+			unowned:     []string{"Phoenix"},
+			dropped: map[string]string{
+				"Mud":     `ingredient "Stone" is tier 2, not below result tier 1`,
+				"Rain":    `ingredient "Cloud" is tier 2, not below result tier 1`,
+				"Ghost":   `ingredient "Spirit" is tier 1, not below result tier 1`,
+				"Spirit":  `ingredient "Ghost" is tier 1, not below result tier 1`,
+				"Phoenix": "needs the Myths and Monsters pack",
+				"Ember":   "needs the Myths and Monsters pack",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+			if tt.ignoreTiers {
+				store.IgnoreTiers()
+			}
+			if tt.packs != nil {
+				if err := store.RestrictToPacks(tt.packs); err != nil {
+					t.Fatalf("RestrictToPacks(%v) error = %v", tt.packs, err)
+				}
+			}
+
+			report := store.Reachability()
+			if !reflect.DeepEqual(report.Unreachable, tt.unreachable) {
+				t.Errorf("unreachable = %v, want %v", report.Unreachable, tt.unreachable)
+			}
+			if !reflect.DeepEqual(report.NoRecipes, tt.noRecipes) {
+				t.Errorf("no recipes = %v, want %v", report.NoRecipes, tt.noRecipes)
+			}
+			if !reflect.DeepEqual(report.Unowned, tt.unowned) {
+				t.Errorf("unowned = %v, want %v", report.Unowned, tt.unowned)
+			}
+			if report.Reachable+len(report.Unreachable)+len(report.Unowned) != report.Elements {
+				t.Errorf("%d reachable, %d unreachable and %d unowned elements do not add up to %d",
+					report.Reachable, len(report.Unreachable), len(report.Unowned), report.Elements)
+			}
+
+			dropped := make(map[string]string)
+			for _, recipe := range report.Dropped {
+				dropped[recipe.Result] = recipe.Reason
+			}
+			if !reflect.DeepEqual(dropped, tt.dropped) {
+				t.Errorf("dropped = %v, want %v", dropped, tt.dropped)
+			}
+		})
+	}
+}
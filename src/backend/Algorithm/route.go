@@ -0,0 +1,111 @@
+package main
+
+import "sort"
+
+// CompletionRoute is an ordering of combinations that discovers every
+// reachable element
+type CompletionRoute struct {
+	Elements     int         `json:"elements"` // Elements discovered by the end, basic elements included
+	Combinations int         `json:"combinations"`
+	Unlocked     []string    `json:"unlocked,omitempty"` // Special elements available from the start
+	Steps        []RouteStep `json:"steps"`
+	Missed       []string    `json:"missed"` // Elements the route cannot discover
+}
+
+// RouteStep is one combination of a completion route
+type RouteStep struct {
+	Step        int      `json:"step"`
+	Ingredients []string `json:"ingredients"`
+	Result      string   `json:"result"`
+	Discovered  int      `json:"discovered"`         // Elements discovered after this step
+	Unlocked    []string `json:"unlocked,omitempty"` // Special elements this step unlocks
+}
+
+// CompletionRoute orders the valid recipes so every reachable element is made
+// exactly once. A combination discovers at most one element, so no route is
+// shorter. Elements are made in rounds from what the previous rounds
+// discovered, lowest tier first, and special elements such as Time join once
+// enough elements have been discovered.
+func (es *ElementStore) CompletionRoute() *CompletionRoute {
+	route := &CompletionRoute{Steps: []RouteStep{}, Missed: []string{}}
+
+	discovered := make(map[string]bool)
+	var pending []string
+	for _, basic := range es.BasicElements {
+		if es.UnlockCondition(basic) != nil {
+			pending = append(pending, basic)
+		} else {
+			discovered[basic] = true
+		}
+	}
+	route.Unlocked, pending = es.unlockReady(discovered, pending)
+
+	recipes := es.RecipesByResult()
+	for {
+		// Everything that can be made from the elements discovered so far
+		var round []Recipe
+		for result, list := range recipes {
+			if discovered[result] {
+				continue
+			}
+			for _, recipe := range list {
+				if discovered[recipe.Ingredients[0]] && discovered[recipe.Ingredients[1]] {
+					round = append(round, recipe)
+					break
+				}
+			}
+		}
+		if len(round) == 0 {
+			break
+		}
+		es.sortRecipes(round)
+
+		for _, recipe := range round {
+			discovered[recipe.Result] = true
+			step := RouteStep{
+				Step:        len(route.Steps) + 1,
+				Ingredients: recipe.Ingredients,
+				Result:      recipe.Result,
+				Discovered:  len(discovered),
+			}
+			step.Unlocked, pending = es.unlockReady(discovered, pending)
+			route.Steps = append(route.Steps, step)
+		}
+	}
+
+	route.Elements = len(discovered)
+	route.Combinations = len(route.Steps)
+	for element := range es.Elements {
+		if !discovered[element] {
+			route.Missed = append(route.Missed, element)
+		}
+	}
+	es.sortByTier(route.Missed)
+
+	return route
+}
+
+// unlockReady moves the pending special elements whose unlock condition is met
+// into discovered, returning them and the elements still pending
+func (es *ElementStore) unlockReady(discovered map[string]bool, pending []string) ([]string, []string) {
+	var unlocked []string
+
+	// An unlocked element counts as discovered and may unlock another
+	for changed := true; changed; {
+		changed = false
+		var waiting []string
+		for _, element := range pending {
+			if len(discovered) >= es.UnlockCondition(element).Discovered {
+				discovered[element] = true
+				unlocked = append(unlocked, element)
+				changed = true
+			} else {
+				waiting = append(waiting, element)
+			}
+		}
+		pending = waiting
+	}
+
+	sort.Strings(unlocked)
+	return unlocked, pending
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompletionRoute(t *testing.T) {
+	store := loadTestStore(t)
+	route := store.CompletionRoute()
+
+	// Every step uses elements already discovered and discovers a new one
+	discovered := make(map[string]bool)
+	for _, basic := range store.BasicElements {
+		discovered[basic] = true
+	}
+	for _, step := range route.Steps {
+		for _, ingredient := range step.Ingredients {
+			if !discovered[ingredient] {
+				t.Errorf("step %d uses %s before it is discovered", step.Step, ingredient)
+			}
+		}
+		if discovered[step.Result] {
+			t.Errorf("step %d makes %s again", step.Step, step.Result)
+		}
+		discovered[step.Result] = true
+		if step.Discovered != len(discovered) {
+			t.Errorf("step %d reports %d elements discovered, want %d", step.Step, step.Discovered, len(discovered))
+		}
+	}
+
+	// One combination per reachable element is the shortest route there is
+	report := store.Reachability()
+	if route.Elements != report.Reachable {
+		t.Errorf("route discovers %d elements, want the %d reachable", route.Elements, report.Reachable)
+	}
+	if want := report.Reachable - len(store.BasicElements); route.Combinations != want {
+		t.Errorf("route has %d combinations, want %d", route.Combinations, want)
+	}
+	if !reflect.DeepEqual(route.Missed, report.Unreachable) {
+		t.Errorf("missed = %v, want the unreachable %v", route.Missed, report.Unreachable)
+	}
+}
+
+func TestCompletionRouteUnlocks(t *testing.T) {
+	store := loadUnlockStore(t)
+	route := store.CompletionRoute()
+
+	// Time joins with the step that discovers the seventh element, and
+	// Eternity with Clock, which needs Time and brings the count to nine
+	unlockedAt := make(map[string]int)
+	for _, step := range route.Steps {
+		for _, element := range step.Unlocked {
+			unlockedAt[element] = step.Discovered
+		}
+	}
+	want := map[string]int{"Time": 7, "Eternity": 9}
+	if !reflect.DeepEqual(unlockedAt, want) {
+		t.Errorf("unlocked at = %v, want %v", unlockedAt, want)
+	}
+	if want := []string{"Infinity", "Forever"}; !reflect.DeepEqual(route.Missed, want) {
+		t.Errorf("missed = %v, want %v", route.Missed, want)
+	}
+}
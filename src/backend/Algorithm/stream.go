@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PathUpdate is one result of a streaming multi-path search
+type PathUpdate struct {
+	Sequence int           // Order in which the result was found, from 1
+	Elapsed  time.Duration // Time from the start of the search until the result was found
+	Result   *SearchResult
+}
+
+// PathStream delivers the results of a multi-path search as they are found.
+// Updates is closed when the search ends, after which Err reports why it
+// ended, if not by finding every path asked for. Callers must either drain
+// Updates or cancel the search's context.
+type PathStream struct {
+	Updates <-chan PathUpdate
+	err     error
+}
+
+// Err returns the error that ended the search, such as ErrSearchTimedOut or a
+// NoPathError. It is only meaningful once Updates is closed.
+func (s *PathStream) Err() error {
+	return s.err
+}
+
+// StreamMultiplePaths runs FindMultiplePathsContext in the background,
+// sending each result as soon as it is known. Under a decomposable cost model trees are
+// built into results on the search's workers while more are enumerated, and
+// sent cheapest first as soon as every cheaper one has been sent. Other
+// models re-rank the whole pool, so their results are sent once it has been
+// enumerated and built.
+func (rf *RankedPathFinder) StreamMultiplePaths(ctx context.Context, target string, maxPaths int) *PathStream {
+	updates := make(chan PathUpdate)
+	stream := &PathStream{Updates: updates}
+
+	go func() {
+		defer close(updates)
+		stream.err = sendRankedPaths(ctx, updates, rf.store, target, maxPaths, rf.costModel, rf.constraints, rf.workers)
+	}()
+
+	return stream
+}
+
+// rankedTree is an enumerated tree waiting for a worker to build its result
+type rankedTree struct {
+	index   int
+	root    *TreeNode
+	visited int // Enumerator's count once the tree was enumerated
+}
+
+// builtResult is the outcome of building one rankedTree
+type builtResult struct {
+	result *SearchResult
+	err    error
+}
+
+// sendRankedPaths sends the results of a multi-path search on updates and
+// returns the error that ended it, if any
+func sendRankedPaths(ctx context.Context, updates chan<- PathUpdate, store *ElementStore, target string, maxPaths int, model CostModel, constraints *SearchConstraints, workers searchWorkers) error {
+	startTime := time.Now()
+
+	// A search stopped before it starts sends nothing
+	if err := searchStopped(ctx); err != nil {
+		return err
+	}
+
+	search, err := newRankedSearch(store, target, maxPaths, model, constraints)
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	send := func(result *SearchResult) bool {
+		update := PathUpdate{Sequence: sent + 1, Elapsed: time.Since(startTime), Result: result}
+		select {
+		case updates <- update:
+			sent++
+			return sent < maxPaths
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if search.decomposable {
+		if err := sendBuilt(ctx, search, workers, startTime, send); err != nil {
+			return err
+		}
+	} else {
+		trees := search.trees(ctx)
+		results, err := search.build(ctx, trees, workers, time.Since(startTime).Milliseconds())
+		if err != nil {
+			return err
+		}
+
+		// Trees not built before the search was stopped are left out
+		finished := results[:0]
+		for _, result := range results {
+			if result != nil {
+				finished = append(finished, result)
+			}
+		}
+
+		rankByCost(finished)
+		for _, result := range finished {
+			if !send(result) {
+				break
+			}
+		}
+	}
+
+	if sent == 0 {
+		return search.noPath(ctx)
+	}
+	return searchStopped(ctx)
+}
+
+// sendBuilt enumerates the trees of search on this goroutine while workers
+// build their results, and calls send with each result in rank order until
+// send returns false or the trees run out. It returns the first build error.
+func sendBuilt(ctx context.Context, search *rankedSearch, workers searchWorkers, startTime time.Time, send func(*SearchResult) bool) error {
+	// Enumeration and building stop once every result needed has been sent
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	jobs := make(chan rankedTree)
+	built := make([]chan builtResult, search.poolSize)
+	for i := range built {
+		built[i] = make(chan builtResult, 1)
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+
+		enumerated := 0
+		search.each(ctx, func(root *TreeNode) bool {
+			tree := rankedTree{index: enumerated, root: root, visited: search.enumerator.visited}
+			select {
+			case jobs <- tree:
+				enumerated++
+				return enumerated < search.poolSize
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	// Workers take trees as they are enumerated and may finish out of
+	// order, so each result goes to the slot of its rank
+	done := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		defer close(done)
+
+		workers.run(ctx, search.poolSize, func(int) {
+			tree, ok := <-jobs
+			if !ok {
+				return
+			}
+			result, err := search.result(tree.root, tree.index, tree.visited, time.Since(startTime).Milliseconds())
+			built[tree.index] <- builtResult{result: result, err: err}
+		})
+	}()
+
+	for _, slot := range built {
+		var next builtResult
+		select {
+		case next = <-slot:
+		case <-done:
+			// Every tree handed out has been built, so an empty slot was never used
+			select {
+			case next = <-slot:
+			default:
+				return nil
+			}
+		}
+
+		if next.err != nil {
+			return next.err
+		}
+		if !send(next.result) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStreamMatchesFindMultiplePaths(t *testing.T) {
+	models := []CostModel{
+		StepsCost{},
+		DepthCost{},
+		DistinctBasicCost{}, // Not decomposable, so the pool is re-ranked before sending
+		&ElementWeightCost{Weights: map[string]float64{"Fire": 2}, DefaultWeight: 1},
+	}
+
+	for _, model := range models {
+		for _, target := range []string{"Brick", "Wall", "Pebble"} {
+			t.Run(model.Name()+"/"+target, func(t *testing.T) {
+				finder := NewRankedPathFinder(loadTestStore(t))
+				finder.SetCostModel(model)
+
+				want, err := finder.FindMultiplePaths(target, 3)
+				if err != nil {
+					t.Fatalf("FindMultiplePaths(%q) error = %v", target, err)
+				}
+
+				stream := finder.StreamMultiplePaths(context.Background(), target, 3)
+				var got []*SearchResult
+				for update := range stream.Updates {
+					if update.Sequence != len(got)+1 {
+						t.Errorf("update %d has sequence %d", len(got)+1, update.Sequence)
+					}
+					got = append(got, update.Result)
+				}
+				if err := stream.Err(); err != nil {
+					t.Fatalf("stream error = %v", err)
+				}
+
+				// The stream sends the same trees in the same order
+				if len(got) != len(want) {
+					t.Fatalf("stream sent %d paths, want %d", len(got), len(want))
+				}
+				for i := range want {
+					if !reflect.DeepEqual(got[i].Path, want[i].Path) || got[i].Cost != want[i].Cost {
+						t.Errorf("path %d = %v costing %v, want %v costing %v", i+1, got[i].Path, got[i].Cost, want[i].Path, want[i].Cost)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestStreamStopsWhenCancelled(t *testing.T) {
+	finder := NewRankedPathFinder(loadTestStore(t))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Cancelling after the first path closes the stream without the rest
+	stream := finder.StreamMultiplePaths(ctx, "Brick", 10)
+	first, ok := <-stream.Updates
+	if !ok || first.Sequence != 1 {
+		t.Fatalf("first update = %+v, %v", first, ok)
+	}
+	cancel()
+	for range stream.Updates {
+	}
+	if err := stream.Err(); err == nil {
+		t.Error("stream of a cancelled search ended without an error")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import "sort"
+
+// TierDiscrepancy is an element whose derived tier differs from its scraped one
+type TierDiscrepancy struct {
+	Element string `json:"element"`
+	Scraped int    `json:"scraped"`
+	Derived int    `json:"derived"` // -1 when no recipe chain reaches the element
+}
+
+// DerivedTiers computes the tier of every element from the recipe graph: basic
+// elements are tier 0 and any other element is one above the highest tier
+// ingredient of its lowest recipe. Only recipes a search may use count, so
+// recipes from packs that are not owned are left out, and so are the elements
+// no recipe chain reaches.
+func (es *ElementStore) DerivedTiers() map[string]int {
+	derived := make(map[string]int)
+	for _, basic := range es.BasicElements {
+		derived[basic] = 0
+	}
+
+	// Tiers only ever decrease, so relaxing until nothing changes terminates
+	changed := true
+	for changed {
+		changed = false
+		for _, recipe := range es.scraped {
+			if !es.searchable(recipe) || es.IsBasicElement(recipe.Result) {
+				continue
+			}
+			if _, exists := es.Elements[recipe.Result]; !exists {
+				continue
+			}
+
+			tier := 0
+			derivable := true
+			for _, ingredient := range recipe.Ingredients {
+				ingredientTier, ok := derived[ingredient]
+				if !ok {
+					derivable = false
+					break
+				}
+				if ingredientTier+1 > tier {
+					tier = ingredientTier + 1
+				}
+			}
+			if !derivable {
+				continue
+			}
+
+			if current, known := derived[recipe.Result]; !known || tier < current {
+				derived[recipe.Result] = tier
+				changed = true
+			}
+		}
+	}
+
+	return derived
+}
+
+// TierDiscrepancies lists the elements whose derived tier differs from the
+// tier scraped from the wiki, lowest scraped tier first. Elements from packs
+// that are not owned are left out.
+func (es *ElementStore) TierDiscrepancies(derived map[string]int) []TierDiscrepancy {
+	discrepancies := []TierDiscrepancy{}
+	for element, scraped := range es.scrapedTiers {
+		if !es.elementOwned(element) {
+			continue
+		}
+		tier, ok := derived[element]
+		if !ok {
+			tier = -1
+		}
+		if tier != scraped {
+			discrepancies = append(discrepancies, TierDiscrepancy{
+				Element: element,
+				Scraped: scraped,
+				Derived: tier,
+			})
+		}
+	}
+
+	sort.Slice(discrepancies, func(i, j int) bool {
+		if discrepancies[i].Scraped != discrepancies[j].Scraped {
+			return discrepancies[i].Scraped < discrepancies[j].Scraped
+		}
+		return discrepancies[i].Element < discrepancies[j].Element
+	})
+	return discrepancies
+}
+
+// UseDerivedTiers replaces the scraped tiers with tiers derived from the
+// recipe graph and filters the recipes again, so elements filed under the
+// wrong wiki heading keep their recipes. Elements no recipe chain reaches keep
+// their scraped tier. It returns the tiers that changed.
+func (es *ElementStore) UseDerivedTiers() []TierDiscrepancy {
+	derived := es.DerivedTiers()
+
+	es.TierMap = make(map[string]int, len(es.scrapedTiers))
+	for element, scraped := range es.scrapedTiers {
+		if tier, ok := derived[element]; ok {
+			es.TierMap[element] = tier
+		} else {
+			es.TierMap[element] = scraped
+		}
+	}
+	es.filterRecipes()
+	es.resolveUnlocks()
+
+	return es.TierDiscrepancies(derived)
+}
+
+// IgnoreTiers switches the store to tier-free search: every scraped recipe
+// with two known ingredients is valid whatever the tiers say, and searches
+// rely on cycle detection instead of tiers always climbing
+func (es *ElementStore) IgnoreTiers() {
+	es.tiersIgnored = true
+	es.filterRecipes()
+	es.resolveUnlocks()
+}
+
+// TiersIgnored reports whether searches ignore tiers
+func (es *ElementStore) TiersIgnored() bool {
+	return es.tiersIgnored
+}
+
+// tierBelow reports whether an element of tier lower may lead to one of tier
+// upper
+func (es *ElementStore) tierBelow(lower, upper int) bool {
+	return es.tiersIgnored || lower < upper
+}
+
+// tierWithin reports whether an element of the given tier may be passed
+// through on the way to one of tier limit
+func (es *ElementStore) tierWithin(tier, limit int) bool {
+	return es.tiersIgnored || tier <= limit
+}
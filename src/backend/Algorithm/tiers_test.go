@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestTierDiscrepancies(t *testing.T) {
+	tests := []struct {
+		name    string
+		element string
+		packs   []string // nil searches every pack
+		want    int      // Derived tier, or the scraped tier when it matches
+	}{
+		{name: "matching tier", element: "Brick", want: 3},
+		{name: "filed too high", element: "Smoke", want: 1},
+		{name: "lower through a pack recipe", element: "Ember", want: 3},
+		{name: "pack recipe not owned", element: "Ember", packs: []string{}, want: 4},
+		{name: "unreachable", element: "Ghost", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+			if tt.packs != nil {
+				if err := store.RestrictToPacks(tt.packs); err != nil {
+					t.Fatalf("RestrictToPacks(%v) error = %v", tt.packs, err)
+				}
+			}
+
+			got := store.scrapedTiers[tt.element]
+			for _, discrepancy := range store.TierDiscrepancies(store.DerivedTiers()) {
+				if discrepancy.Element == tt.element {
+					got = discrepancy.Derived
+				}
+			}
+			if got != tt.want {
+				t.Errorf("derived tier of %s = %d, want %d", tt.element, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTierDiscrepanciesSkipUnownedPacks(t *testing.T) {
+	store := loadTestStore(t)
+	if err := store.RestrictToPacks(nil); err != nil {
+		t.Fatalf("RestrictToPacks error = %v", err)
+	}
+
+	for _, discrepancy := range store.TierDiscrepancies(store.DerivedTiers()) {
+		if discrepancy.Element == "Phoenix" {
+			t.Errorf("pack element Phoenix reported without its pack: %+v", discrepancy)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RecipeTreeFinder searches the recipe graph as an AND-OR graph: every element
+// is an OR-node over its recipes and every recipe is an AND-node over both of
+// its ingredients. Unlike the path finders, the result resolves every
+// ingredient down to basic elements, so it can be followed in the game.
+type RecipeTreeFinder struct {
+	store *ElementStore
+}
+
+// treeChoice is the best known way to make an element
+type treeChoice struct {
+	Cost   int // Number of combinations in the full tree
+	Recipe Recipe
+	Basic  bool
+}
+
+// NewRecipeTreeFinder creates finder instance
+func NewRecipeTreeFinder(store *ElementStore) *RecipeTreeFinder {
+	return &RecipeTreeFinder{store: store}
+}
+
+// FindShortestPath finds the smallest complete recipe tree for target
+func (tf *RecipeTreeFinder) FindShortestPath(target string) (*SearchResult, error) {
+	return tf.FindShortestPathContext(context.Background(), target)
+}
+
+// FindShortestPathContext finds the smallest complete recipe tree for target,
+// stopping once ctx is done
+func (tf *RecipeTreeFinder) FindShortestPathContext(ctx context.Context, target string) (*SearchResult, error) {
+	startTime := time.Now()
+
+	// Check target exists
+	if _, exists := tf.store.Elements[target]; !exists {
+		return nil, ErrElementNotFound
+	}
+
+	if len(tf.store.BasicElements) == 0 {
+		return nil, ErrNoBasicElements
+	}
+
+	// Resolve the cheapest tree for every element
+	best, err := tf.solveContext(ctx, tf.store.BasicElements)
+	if err != nil {
+		return timedOutResult(len(best), startTime), err
+	}
+	if _, found := best[target]; !found {
+		return nil, tf.store.explainNoPath(target, nil, "")
+	}
+
+	// Expand the choices into a full tree and a buildable order
+	root := tf.buildTree(target, best)
+	if err := tf.store.VerifyTree(root).Err(); err != nil {
+		return nil, err
+	}
+	path := RecipeTreeOrder(root)
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	return &SearchResult{
+		Path:          path,
+		VisitedNodes:  len(best),
+		ExecutionTime: executionTime,
+		TreeStructure: buildTreeStructureFromNode(tf.store, root),
+		Tree:          root,
+	}, nil
+}
+
+// solve computes the cheapest tree for every derivable element by relaxing
+// recipes until no cost improves. Relaxation terminates on cyclic graphs too,
+// since every combination adds a positive cost.
+func (tf *RecipeTreeFinder) solve() map[string]treeChoice {
+	return tf.solveFrom(tf.store.BasicElements)
+}
+
+// solveFrom is solve with the given elements as the leaves of every tree
+func (tf *RecipeTreeFinder) solveFrom(leaves []string) map[string]treeChoice {
+	best, _ := tf.solveContext(context.Background(), leaves)
+	return best
+}
+
+// solveContext is solveFrom stopping between relaxation passes once ctx is
+// done, with the costs found so far
+func (tf *RecipeTreeFinder) solveContext(ctx context.Context, leaves []string) (map[string]treeChoice, error) {
+	best := make(map[string]treeChoice)
+	for _, leaf := range leaves {
+		best[leaf] = treeChoice{Basic: true}
+	}
+
+	changed := true
+	for changed {
+		if err := searchStopped(ctx); err != nil {
+			return best, err
+		}
+
+		changed = false
+		for _, recipe := range tf.store.Recipes {
+			if len(recipe.Ingredients) != 2 {
+				continue
+			}
+
+			// Basic elements are always leaves
+			current, known := best[recipe.Result]
+			if known && current.Basic {
+				continue
+			}
+
+			// Both ingredients must already be derivable
+			left, leftOk := best[recipe.Ingredients[0]]
+			right, rightOk := best[recipe.Ingredients[1]]
+			if !leftOk || !rightOk {
+				continue
+			}
+
+			cost := 1 + left.Cost + right.Cost
+			if !known || cost < current.Cost {
+				best[recipe.Result] = treeChoice{Cost: cost, Recipe: recipe}
+				changed = true
+			}
+		}
+	}
+
+	return best, nil
+}
+
+// buildTree expands the chosen recipes into a full tree rooted at element
+func (tf *RecipeTreeFinder) buildTree(element string, best map[string]treeChoice) *TreeNode {
+	choice := best[element]
+
+	node := &TreeNode{
+		Element:  element,
+		Children: []*TreeNode{},
+		IsResult: !choice.Basic,
+		Tier:     tf.store.GetElementTier(element),
+	}
+	if elem, exists := tf.store.Elements[element]; exists {
+		node.ImageURL = elem.ImageURL
+	}
+
+	if choice.Basic {
+		return node
+	}
+
+	for _, ingredient := range choice.Recipe.Ingredients {
+		node.Children = append(node.Children, tf.buildTree(ingredient, best))
+	}
+
+	return node
+}
+
+// RecipeTreeOrder lists the combinations of a recipe tree in an order the
+// player can follow: ingredients are always made before the element that
+// needs them, and an element used several times is only made once.
+func RecipeTreeOrder(root *TreeNode) []Recipe {
+	var path []Recipe
+	made := make(map[string]bool)
+
+	var walk func(node *TreeNode)
+	walk = func(node *TreeNode) {
+		if !node.IsResult || made[node.Element] {
+			return
+		}
+
+		ingredients := make([]string, 0, len(node.Children))
+		for _, child := range node.Children {
+			walk(child)
+			ingredients = append(ingredients, child.Element)
+		}
+
+		made[node.Element] = true
+		path = append(path, Recipe{
+			Ingredients: ingredients,
+			Result:      node.Element,
+		})
+	}
+
+	walk(root)
+	return path
+}
+
+// countTreeCombinations counts every combination in a tree, including
+// intermediates that are made more than once
+func countTreeCombinations(node *TreeNode) int {
+	if node == nil || !node.IsResult {
+		return 0
+	}
+
+	count := 1
+	for _, child := range node.Children {
+		count += countTreeCombinations(child)
+	}
+	return count
+}
+
+// buildTreeStructureFromNode creates the visualization tree from a recipe tree
+func buildTreeStructureFromNode(store *ElementStore, root *TreeNode) interface{} {
+	// Build tree for Next.js visualization - target as root, basics as leaves
+	nodes := []map[string]interface{}{}
+	edges := []map[string]interface{}{}
+	nodeMap := make(map[string]bool)
+	edgeMap := make(map[string]bool)
+
+	var buildNodes func(node *TreeNode, nodeType string)
+	buildNodes = func(node *TreeNode, nodeType string) {
+		if !nodeMap[node.Element] {
+			nodes = append(nodes, store.annotateUnlock(map[string]interface{}{
+				"id":       node.Element,
+				"label":    node.Element,
+				"type":     nodeType,
+				"tier":     node.Tier,
+				"imageUrl": node.ImageURL,
+			}, node.Element))
+			nodeMap[node.Element] = true
+		}
+
+		for _, child := range node.Children {
+			childType := "ingredient"
+			if store.IsBasicElement(child.Element) {
+				childType = "basic" // Mark basic elements
+			}
+			buildNodes(child, childType)
+
+			// Add edge from ingredient to result element once
+			edgeID := fmt.Sprintf("%s-%s", child.Element, node.Element)
+			if !edgeMap[edgeID] {
+				edges = append(edges, map[string]interface{}{
+					"id":     edgeID,
+					"source": child.Element,
+					"target": node.Element,
+				})
+				edgeMap[edgeID] = true
+			}
+		}
+	}
+
+	buildNodes(root, "target")
+
+	return map[string]interface{}{
+		"nodes":   nodes,
+		"edges":   edges,
+		"target":  root.Element,
+		"recipes": RecipeTreeOrder(root),
+	}
+}
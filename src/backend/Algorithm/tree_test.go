@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRecipeTreeFinder(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		want   int // Combinations in the tree, counting repeated intermediates
+		err    error
+	}{
+		{name: "basic element", target: "Fire", want: 0},
+		{name: "basic ingredients", target: "Steam", want: 1},
+		{name: "made ingredient", target: "Stone", want: 2},
+		{name: "cheapest recipe", target: "Brick", want: 3},
+		{name: "both ingredients resolved", target: "Wall", want: 5},
+		{name: "repeated ingredient", target: "Pebble", want: 5},
+		{name: "no valid recipe", target: "Rain", err: ErrNoPathFound},
+		{name: "cycle nothing makes", target: "Ghost", err: ErrNoPathFound},
+		{name: "unknown element", target: "Gold", err: ErrElementNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+
+			result, err := NewRecipeTreeFinder(store).FindShortestPath(tt.target)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("FindShortestPath(%q) error = %v, want %v", tt.target, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindShortestPath(%q) error = %v", tt.target, err)
+			}
+			if got := countTreeCombinations(result.Tree); got != tt.want {
+				t.Errorf("FindShortestPath(%q) tree has %d combinations, want %d", tt.target, got, tt.want)
+			}
+
+			// Unlike a path, the tree resolves both ingredients of every
+			// combination, so all its leaves are basic elements
+			var walk func(node *TreeNode)
+			walk = func(node *TreeNode) {
+				if len(node.Children) == 0 && !store.IsBasicElement(node.Element) {
+					t.Errorf("leaf %s is not a basic element", node.Element)
+				}
+				for _, child := range node.Children {
+					walk(child)
+				}
+			}
+			walk(result.Tree)
+
+			if err := store.VerifyPath(result.Path, tt.target).Err(); err != nil {
+				t.Errorf("build order is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestRecipeTreeFinderSmallest(t *testing.T) {
+	store := loadTestStore(t)
+	finder := NewRecipeTreeFinder(store)
+
+	// The cheapest tree the enumerator finds under the steps model is the
+	// smallest tree, which the finder must match for every element
+	for target := range store.Elements {
+		trees := NewTreeEnumerator(store, StepsCost{}, nil).Top(context.Background(), target, 1)
+		result, err := finder.FindShortestPath(target)
+		if len(trees) == 0 {
+			if !errors.Is(err, ErrNoPathFound) {
+				t.Errorf("FindShortestPath(%q) error = %v, want %v", target, err, ErrNoPathFound)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("FindShortestPath(%q) error = %v", target, err)
+		}
+
+		want := countTreeCombinations(trees[0])
+		if got := countTreeCombinations(result.Tree); got != want {
+			t.Errorf("FindShortestPath(%q) tree has %d combinations, want %d", target, got, want)
+		}
+	}
+}
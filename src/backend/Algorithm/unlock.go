@@ -0,0 +1,88 @@
+package main
+
+import "fmt"
+
+// UnlockCondition describes how a special element without recipes, such as
+// Time, is unlocked
+type UnlockCondition struct {
+	Text       string `json:"text"`
+	Discovered int    `json:"discovered,omitempty"` // Elements to discover first, 0 if not stated
+}
+
+// UnlockCondition returns the unlock condition of element, or nil if it has none
+func (es *ElementStore) UnlockCondition(element string) *UnlockCondition {
+	if elem, exists := es.Elements[element]; exists {
+		return elem.Unlock
+	}
+	return nil
+}
+
+// resolveUnlocks keeps a special element among the basic elements only while
+// its unlock condition can be met without it. Conditions that state no
+// element count cannot be checked and are assumed to be met. The basic
+// elements keep their file order.
+func (es *ElementStore) resolveUnlocks() {
+	current := make(map[string]bool, len(es.BasicElements)+len(es.Locked))
+	for _, element := range append(append([]string{}, es.BasicElements...), es.Locked...) {
+		current[element] = true
+	}
+	var candidates []string
+	for _, element := range es.basics {
+		if current[element] {
+			candidates = append(candidates, element)
+		}
+	}
+
+	locked := make(map[string]bool)
+	for _, element := range candidates {
+		if es.UnlockCondition(element) != nil {
+			locked[element] = true
+		}
+	}
+
+	// Unlocking one element may let more be discovered, so repeat until nothing changes
+	for changed := true; changed; {
+		changed = false
+
+		es.BasicElements = es.BasicElements[:0]
+		for _, element := range candidates {
+			if !locked[element] {
+				es.BasicElements = append(es.BasicElements, element)
+			}
+		}
+		discovered := len(NewRecipeTreeFinder(es).solve())
+
+		for _, element := range candidates {
+			if locked[element] && discovered >= es.UnlockCondition(element).Discovered {
+				delete(locked, element)
+				changed = true
+			}
+		}
+	}
+
+	es.BasicElements = es.BasicElements[:0]
+	es.Locked = nil
+	for _, element := range candidates {
+		if locked[element] {
+			es.Locked = append(es.Locked, element)
+		} else {
+			es.BasicElements = append(es.BasicElements, element)
+		}
+	}
+}
+
+// unlockNote describes the unlock condition of element for printed trees
+func (es *ElementStore) unlockNote(element string) string {
+	if unlock := es.UnlockCondition(element); unlock != nil {
+		return fmt.Sprintf(" [%s]", unlock.Text)
+	}
+	return ""
+}
+
+// annotateUnlock adds the unlock condition of element to a visualization node
+func (es *ElementStore) annotateUnlock(node map[string]interface{}, element string) map[string]interface{} {
+	if unlock := es.UnlockCondition(element); unlock != nil {
+		node["unlock"] = unlock
+	}
+	return node
+}
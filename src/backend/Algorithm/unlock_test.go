@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// loadUnlockStore loads the element set in testdata with special elements.
+// The four basic elements make Mud, Steam and Lava, which is the 7 elements
+// Time needs. Time makes Clock, which brings the count to the 9 Eternity
+// needs, and nothing gets near the 100 Infinity needs.
+func loadUnlockStore(t *testing.T) *ElementStore {
+	t.Helper()
+
+	store, err := NewElementStore("testdata/unlock.json")
+	if err != nil {
+		t.Fatalf("loading unlock elements: %v", err)
+	}
+	return store
+}
+
+func TestResolveUnlocks(t *testing.T) {
+	store := loadUnlockStore(t)
+
+	// Unlocking Time makes enough elements to unlock Eternity
+	wantBasic := []string{"Air", "Earth", "Fire", "Water", "Time", "Eternity"}
+	if !reflect.DeepEqual(store.BasicElements, wantBasic) {
+		t.Errorf("basic elements = %v, want %v", store.BasicElements, wantBasic)
+	}
+	if want := []string{"Infinity"}; !reflect.DeepEqual(store.Locked, want) {
+		t.Errorf("locked = %v, want %v", store.Locked, want)
+	}
+}
+
+func TestUnlockConditionInSearch(t *testing.T) {
+	store := loadUnlockStore(t)
+
+	result, err := NewRecipeTreeFinder(store).FindShortestPath("Clock")
+	if err != nil {
+		t.Fatalf("FindShortestPath(Clock) error = %v", err)
+	}
+
+	// The tree reports the condition on the Time leaf
+	structure := result.TreeStructure.(map[string]interface{})
+	found := false
+	for _, node := range structure["nodes"].([]map[string]interface{}) {
+		if node["id"] != "Time" {
+			continue
+		}
+		found = true
+		unlock, ok := node["unlock"].(*UnlockCondition)
+		if !ok || unlock.Discovered != 7 {
+			t.Errorf("Time node unlock = %v, want the condition to discover 7 elements", node["unlock"])
+		}
+	}
+	if !found {
+		t.Errorf("tree has no Time node: %v", structure["nodes"])
+	}
+
+	// A locked element is not available, so nothing it makes can be reached
+	if _, err := NewRecipeTreeFinder(store).FindShortestPath("Forever"); !errors.Is(err, ErrNoPathFound) {
+		t.Errorf("FindShortestPath(Forever) error = %v, want %v", err, ErrNoPathFound)
+	}
+}
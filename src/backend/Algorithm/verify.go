@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidPath is returned when a path or tree fails verification
+var ErrInvalidPath = errors.New("invalid recipe path")
+
+// IssueKind classifies a problem found while verifying a path
+type IssueKind string
+
+// Problems a verification can report
+const (
+	IssueMalformed         IssueKind = "malformed"          // A step without exactly two ingredients
+	IssueUnknownElement    IssueKind = "unknown element"    // An element missing from the store
+	IssueUnknownRecipe     IssueKind = "unknown recipe"     // A combination that is not a valid recipe
+	IssueTier              IssueKind = "tier"               // An ingredient not below its result's tier
+	IssueMissingIngredient IssueKind = "missing ingredient" // An ingredient neither at hand nor made earlier
+	IssueTargetNotMade     IssueKind = "target not made"    // No step makes the target
+)
+
+// VerificationIssue is one problem with a path. Step numbers the offending
+// step from 1, or is 0 for problems with the path as a whole.
+type VerificationIssue struct {
+	Step    int       `json:"step"`
+	Kind    IssueKind `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// Verification lists every problem found in a path or tree
+type Verification struct {
+	Target string              `json:"target"`
+	Steps  int                 `json:"steps"`
+	Valid  bool                `json:"valid"`
+	Issues []VerificationIssue `json:"issues"`
+}
+
+// VerifyPath checks that every step of path is a valid recipe of the store
+// whose ingredients are basic, among the elements at hand or made by an
+// earlier step, and that target is made. Searches on a constrained view also
+// reject the recipes the constraints rule out.
+func (es *ElementStore) VerifyPath(path []Recipe, target string, have ...string) *Verification {
+	v := &Verification{Target: target, Steps: len(path), Issues: []VerificationIssue{}}
+
+	available := make(map[string]bool)
+	for _, element := range es.BasicElements {
+		available[element] = true
+	}
+	for _, element := range have {
+		available[element] = true
+	}
+	recipes := es.RecipesByResult()
+
+	for i, step := range path {
+		if len(step.Ingredients) != 2 {
+			v.add(i+1, IssueMalformed, "%s has %d ingredients, expected 2", step.Result, len(step.Ingredients))
+			available[step.Result] = true
+			continue
+		}
+
+		known := true
+		for _, element := range append([]string{step.Result}, step.Ingredients...) {
+			if _, exists := es.Elements[element]; !exists {
+				v.add(i+1, IssueUnknownElement, "%q is not a known element", element)
+				known = false
+			}
+		}
+		if known {
+			es.verifyStep(v, i+1, step, recipes[step.Result])
+		}
+
+		for j, ingredient := range step.Ingredients {
+			// The same missing ingredient used twice is reported once
+			if j == 1 && ingredient == step.Ingredients[0] {
+				break
+			}
+			if !available[ingredient] {
+				v.add(i+1, IssueMissingIngredient, "%s is used to make %s before it is made", ingredient, step.Result)
+			}
+		}
+		available[step.Result] = true
+	}
+
+	if !available[target] {
+		v.add(0, IssueTargetNotMade, "no step makes %s", target)
+	}
+
+	v.Valid = len(v.Issues) == 0
+	return v
+}
+
+// VerifyTree checks a recipe tree the same way as a path. Every node with
+// children is a combination of them and every leaf must be basic or among the
+// elements at hand.
+func (es *ElementStore) VerifyTree(root *TreeNode, have ...string) *Verification {
+	var path []Recipe
+
+	var walk func(node *TreeNode)
+	walk = func(node *TreeNode) {
+		if len(node.Children) == 0 {
+			return
+		}
+
+		ingredients := make([]string, 0, len(node.Children))
+		for _, child := range node.Children {
+			walk(child)
+			ingredients = append(ingredients, child.Element)
+		}
+		path = append(path, Recipe{Ingredients: ingredients, Result: node.Element})
+	}
+
+	walk(root)
+	return es.VerifyPath(path, root.Element, have...)
+}
+
+// verifyStep checks that step is one of the valid recipes for its result and
+// that it respects the tiers
+func (es *ElementStore) verifyStep(v *Verification, number int, step Recipe, recipes []Recipe) {
+	if !es.ValidateTierConstraint(step.Ingredients, step.Result) {
+		v.add(number, IssueTier, "%s + %s → %s breaks the tier order (tiers %d, %d → %d)",
+			step.Ingredients[0], step.Ingredients[1], step.Result,
+			es.GetElementTier(step.Ingredients[0]), es.GetElementTier(step.Ingredients[1]), es.GetElementTier(step.Result))
+	}
+
+	for _, recipe := range recipes {
+		if sameIngredients(recipe.Ingredients, step.Ingredients) {
+			return
+		}
+	}
+	v.add(number, IssueUnknownRecipe, "%s + %s does not make %s", step.Ingredients[0], step.Ingredients[1], step.Result)
+}
+
+// add records an issue
+func (v *Verification) add(step int, kind IssueKind, format string, args ...interface{}) {
+	v.Issues = append(v.Issues, VerificationIssue{
+		Step:    step,
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Err returns an error describing the first issue that is not one of the
+// ignored kinds, or nil if there is none
+func (v *Verification) Err(ignore ...IssueKind) error {
+	for _, issue := range v.Issues {
+		ignored := false
+		for _, kind := range ignore {
+			if issue.Kind == kind {
+				ignored = true
+				break
+			}
+		}
+		if ignored {
+			continue
+		}
+
+		if issue.Step == 0 {
+			return fmt.Errorf("%w for %s: %s", ErrInvalidPath, v.Target, issue.Message)
+		}
+		return fmt.Errorf("%w for %s: step %d: %s", ErrInvalidPath, v.Target, issue.Step, issue.Message)
+	}
+	return nil
+}
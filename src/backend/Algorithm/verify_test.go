@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// issueAt is the part of a VerificationIssue the tests compare
+type issueAt struct {
+	Step int
+	Kind IssueKind
+}
+
+// issuesOf drops the messages of v's issues
+func issuesOf(v *Verification) []issueAt {
+	issues := []issueAt{}
+	for _, issue := range v.Issues {
+		issues = append(issues, issueAt{Step: issue.Step, Kind: issue.Kind})
+	}
+	return issues
+}
+
+// step is a recipe written the way paths list it
+func step(first, second, result string) Recipe {
+	return Recipe{Ingredients: []string{first, second}, Result: result}
+}
+
+func TestVerifyPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   []Recipe
+		target string
+		have   []string
+		want   []issueAt
+	}{
+		{
+			name:   "valid path",
+			path:   []Recipe{step("Earth", "Water", "Mud"), step("Mud", "Fire", "Stone")},
+			target: "Stone",
+			want:   []issueAt{},
+		},
+		{
+			name:   "basic target",
+			target: "Fire",
+			want:   []issueAt{},
+		},
+		{
+			name:   "malformed step",
+			path:   []Recipe{{Ingredients: []string{"Earth"}, Result: "Mud"}},
+			target: "Mud",
+			want:   []issueAt{{1, IssueMalformed}},
+		},
+		{
+			name:   "unknown element",
+			path:   []Recipe{step("Earth", "Gold", "Mud")},
+			target: "Mud",
+			want:   []issueAt{{1, IssueUnknownElement}, {1, IssueMissingIngredient}},
+		},
+		{
+			name:   "unknown recipe",
+			path:   []Recipe{step("Air", "Water", "Mud")},
+			target: "Mud",
+			want:   []issueAt{{1, IssueUnknownRecipe}},
+		},
+		{
+			name:   "dropped recipe breaks tiers",
+			path:   []Recipe{step("Fire", "Water", "Steam"), step("Steam", "Air", "Cloud"), step("Cloud", "Water", "Rain")},
+			target: "Rain",
+			want:   []issueAt{{3, IssueTier}, {3, IssueUnknownRecipe}},
+		},
+		{
+			name:   "ingredient made later",
+			path:   []Recipe{step("Mud", "Fire", "Stone"), step("Earth", "Water", "Mud")},
+			target: "Stone",
+			want:   []issueAt{{1, IssueMissingIngredient}},
+		},
+		{
+			name:   "missing ingredient used twice",
+			path:   []Recipe{step("Stone", "Stone", "Pebble")},
+			target: "Pebble",
+			want:   []issueAt{{1, IssueMissingIngredient}},
+		},
+		{
+			name:   "ingredient at hand",
+			path:   []Recipe{step("Stone", "Stone", "Pebble")},
+			target: "Pebble",
+			have:   []string{"Stone"},
+			want:   []issueAt{},
+		},
+		{
+			name:   "target not made",
+			path:   []Recipe{step("Earth", "Water", "Mud")},
+			target: "Stone",
+			want:   []issueAt{{0, IssueTargetNotMade}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+
+			v := store.VerifyPath(tt.path, tt.target, tt.have...)
+			if got := issuesOf(v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VerifyPath issues = %v, want %v", got, tt.want)
+			}
+			if v.Valid != (len(tt.want) == 0) {
+				t.Errorf("VerifyPath valid = %v with issues %v", v.Valid, v.Issues)
+			}
+			if err := v.Err(); (err == nil) != v.Valid || (err != nil && !errors.Is(err, ErrInvalidPath)) {
+				t.Errorf("Err() = %v for valid = %v", err, v.Valid)
+			}
+		})
+	}
+}
+
+func TestVerifyTree(t *testing.T) {
+	leaf := func(element string) *TreeNode { return &TreeNode{Element: element} }
+	node := func(element string, first, second *TreeNode) *TreeNode {
+		return &TreeNode{Element: element, Children: []*TreeNode{first, second}, IsResult: true}
+	}
+
+	tests := []struct {
+		name string
+		tree *TreeNode
+		have []string
+		want []issueAt
+	}{
+		{
+			name: "valid tree",
+			tree: node("Brick", node("Stone", node("Lava", leaf("Earth"), leaf("Fire")), leaf("Air")), leaf("Fire")),
+			want: []issueAt{},
+		},
+		{
+			name: "leaf not basic",
+			tree: node("Stone", leaf("Mud"), leaf("Fire")),
+			want: []issueAt{{1, IssueMissingIngredient}},
+		},
+		{
+			name: "leaf at hand",
+			tree: node("Stone", leaf("Mud"), leaf("Fire")),
+			have: []string{"Mud"},
+			want: []issueAt{},
+		},
+		{
+			name: "wrong children",
+			tree: node("Stone", leaf("Earth"), leaf("Water")),
+			want: []issueAt{{1, IssueUnknownRecipe}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := loadTestStore(t)
+
+			if got := issuesOf(store.VerifyTree(tt.tree, tt.have...)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VerifyTree issues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
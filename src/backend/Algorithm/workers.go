@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// WorkerPool bounds how many goroutines every search sharing it may run at
+// once, so simultaneous searches cannot together exceed its size
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// NewWorkerPool creates a pool of size workers, or one per CPU if size is
+// not positive
+func NewWorkerPool(size int) *WorkerPool {
+	if size <= 0 {
+		size = runtime.NumCPU()
+	}
+	return &WorkerPool{slots: make(chan struct{}, size)}
+}
+
+// DefaultWorkerPool is used by every search not given a pool of its own
+var DefaultWorkerPool = NewWorkerPool(0)
+
+// SearchWorkerLimit is the most workers of its pool one search started by
+// the CLI or a command uses, 0 for no limit of its own
+var SearchWorkerLimit int
+
+// Size returns the most workers the pool runs at once
+func (p *WorkerPool) Size() int {
+	return cap(p.slots)
+}
+
+// Run calls work for every index below n on at most limit of the pool's
+// workers, or on as many as the pool allows if limit is not positive. Once
+// ctx is done no more work is started. Run returns when every call it
+// started has returned.
+func (p *WorkerPool) Run(ctx context.Context, n, limit int, work func(index int)) {
+	if limit <= 0 || limit > p.Size() {
+		limit = p.Size()
+	}
+	if limit > n {
+		limit = n
+	}
+
+	// The search's own slots are taken first, so a search waiting for the
+	// pool holds at most one of them
+	own := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		if !acquire(ctx, own) {
+			break
+		}
+		if !acquire(ctx, p.slots) {
+			<-own
+			break
+		}
+		wg.Add(1)
+
+		go func(index int) {
+			defer wg.Done()
+			defer func() {
+				<-p.slots
+				<-own
+			}()
+
+			if ctx.Err() != nil {
+				return
+			}
+			work(index)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// acquire takes one of slots, giving up once ctx is done
+func acquire(ctx context.Context, slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// searchWorkers is the share of a worker pool one search may use
+type searchWorkers struct {
+	pool  *WorkerPool // Nil for DefaultWorkerPool
+	limit int         // Most workers the search uses, 0 for no limit of its own
+}
+
+// size returns how many workers the search may use at once
+func (w searchWorkers) size() int {
+	pool := w.pool
+	if pool == nil {
+		pool = DefaultWorkerPool
+	}
+	if w.limit > 0 && w.limit < pool.Size() {
+		return w.limit
+	}
+	return pool.Size()
+}
+
+// run calls work for every index below n on the search's workers
+func (w searchWorkers) run(ctx context.Context, n int, work func(index int)) {
+	pool := w.pool
+	if pool == nil {
+		pool = DefaultWorkerPool
+	}
+	pool.Run(ctx, n, w.limit, work)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyTracker records the most jobs running at once. Each job waits
+// briefly so overlapping jobs are seen.
+type concurrencyTracker struct {
+	running, most int32
+}
+
+func (c *concurrencyTracker) job(index int) {
+	now := atomic.AddInt32(&c.running, 1)
+	for {
+		seen := atomic.LoadInt32(&c.most)
+		if now <= seen || atomic.CompareAndSwapInt32(&c.most, seen, now) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	atomic.AddInt32(&c.running, -1)
+}
+
+func TestWorkerPoolLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		limit int
+		want  int32 // Most jobs allowed at once
+	}{
+		{name: "pool size", size: 3, limit: 0, want: 3},
+		{name: "search limit", size: 4, limit: 2, want: 2},
+		{name: "limit above pool", size: 2, limit: 5, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tracker concurrencyTracker
+			NewWorkerPool(tt.size).Run(context.Background(), 20, tt.limit, tracker.job)
+			if tracker.most > tt.want {
+				t.Errorf("%d jobs ran at once, want at most %d", tracker.most, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkerPoolSharedBySearches(t *testing.T) {
+	pool := NewWorkerPool(3)
+
+	// Searches running at the same time together stay within the pool
+	var tracker concurrencyTracker
+	var wg sync.WaitGroup
+	for search := 0; search < 4; search++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.Run(context.Background(), 10, 2, tracker.job)
+		}()
+	}
+	wg.Wait()
+
+	if tracker.most > 3 {
+		t.Errorf("%d jobs ran at once across searches, want at most 3", tracker.most)
+	}
+}
+
+func TestWorkerPoolStopsOnCancel(t *testing.T) {
+	pool := NewWorkerPool(1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Once the context is done no more jobs start
+	var ran int32
+	pool.Run(ctx, 10, 0, func(index int) {
+		if atomic.AddInt32(&ran, 1) == 2 {
+			cancel()
+		}
+	})
+	if ran > 3 {
+		t.Errorf("%d jobs ran after cancelling on the second", ran)
+	}
+}
@@ -2,18 +2,50 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 )
 
 func main() {
+	patch := flag.Bool("patch", false, "apply the known unlock conditions to the existing elements.json instead of scraping")
+	flag.Parse()
+
+	if *patch {
+		patchElements()
+		return
+	}
+
 	elements, err := ScrapeElements() // Direct call, no package name needed
 	if err != nil {
 		fmt.Println("Failed scraping:", err)
 		return
 	}
 
+	writeElements(elements)
+	fmt.Printf("Scraping complete: %d tier groups saved to elements.json\n", len(elements))
+}
+
+// patchElements applies knownUnlocks to elements.json, for data scraped
+// before the fallback existed or when the wiki cannot be reached
+func patchElements() {
+	data, err := os.ReadFile("elements.json")
+	if err != nil {
+		log.Fatalf("Error reading file: %v", err)
+	}
+
+	var elements []ElementGroup
+	if err := json.Unmarshal(data, &elements); err != nil {
+		log.Fatalf("Error decoding JSON: %v", err)
+	}
+
+	patched := applyKnownUnlocks(elements)
+	writeElements(elements)
+	fmt.Printf("Patch complete: %d elements updated in elements.json %v\n", len(patched), patched)
+}
+
+func writeElements(elements []ElementGroup) {
 	file, err := os.Create("elements.json")
 	if err != nil {
 		log.Fatalf("Error creating file: %v", err)
@@ -25,6 +57,4 @@ func main() {
 	if err := encoder.Encode(elements); err != nil {
 		log.Fatalf("Error encoding JSON: %v", err)
 	}
-
-	fmt.Printf("Scraping complete: %d tier groups saved to elements.json\n", len(elements))
 }
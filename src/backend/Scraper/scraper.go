@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,11 +15,37 @@ import (
 )
 
 type Element struct {
-	Name     string     `json:"name"`
-	Recipes  [][]string `json:"recipes"`
-	ImageURL string     `json:"imageUrl"` // URL to element's image
+	Name     string           `json:"name"`
+	Recipes  [][]string       `json:"recipes"`
+	ImageURL string           `json:"imageUrl"`         // URL to element's image
+	Unlock   *UnlockCondition `json:"unlock,omitempty"` // Set for special elements without recipes
+	Pack     string           `json:"pack,omitempty"`   // Expansion pack, empty for base-game content
+
+	// Recipes that need an expansion pack, grouped by pack. They are also listed in Recipes.
+	PackRecipes map[string][][]string `json:"packRecipes,omitempty"`
 }
 
+// UnlockCondition describes how a special element without recipes is unlocked
+type UnlockCondition struct {
+	Text       string `json:"text"`
+	Discovered int    `json:"discovered,omitempty"` // Elements to discover first, 0 if not stated
+}
+
+// discoveredPattern finds the element count in texts like "discover 100 elements"
+var discoveredPattern = regexp.MustCompile(`(\d+)\s+elements`)
+
+// knownUnlocks holds unlock conditions the element list leaves out. Time's
+// cell on the list is empty; the condition comes from the element's own page.
+// The scraper falls back to these, and main -patch applies them to an
+// existing elements.json without scraping again.
+var knownUnlocks = map[string]UnlockCondition{
+	"Time": {Text: "Unlock by discovering 100 elements", Discovered: 100},
+}
+
+// expansionPacks lists the paid packs whose elements and recipes are mixed
+// into the element list
+var expansionPacks = []string{"Myths and Monsters"}
+
 type ElementGroup struct {
 	TierNum  int       `json:"tierNum"`
 	Elements []Element `json:"elements"`
@@ -55,9 +83,13 @@ func ScrapeElements() ([]ElementGroup, error) {
 	recipeMap := make(map[string][][]string)
 	imageURLMap := make(map[string]string) // Maps element name to image URL
 	tierNumMap := make(map[string]int)     // Maps element name to numerical tier
+	unlockMap := make(map[string]*UnlockCondition)
+	packMap := make(map[string]string)                      // Maps element name to its expansion pack
+	packRecipeMap := make(map[string]map[string][][]string) // Maps element name to its recipes by pack
 
 	// Extract tiers by tracking headers and tables
 	currentTierNum := -1
+	currentPack := ""
 
 	doc.Find("h2, h3, table.list-table").Each(func(i int, s *goquery.Selection) {
 		// Check if it's a heading (h2 or h3)
@@ -65,6 +97,14 @@ func ScrapeElements() ([]ElementGroup, error) {
 			headingText := cleanText(s.Text())
 			log.Printf("Found heading: %s", headingText)
 
+			// A pack section lasts until the next section heading
+			if s.Is("h2") {
+				currentPack = packOf(s)
+				if currentPack != "" {
+					log.Printf("Current pack: %s", currentPack)
+				}
+			}
+
 			// Parse tier from heading
 			if strings.Contains(strings.ToLower(headingText), "starting elements") {
 				currentTierNum = 0
@@ -134,8 +174,11 @@ func ScrapeElements() ([]ElementGroup, error) {
 				// First cell contains the element name and image
 				elementCell := cells.Eq(0)
 
-				// Extract element name from the last link in the cell
-				elementNode := elementCell.Find("a").Last()
+				// Extract element name from the last link in the cell that
+				// is not a pack marker
+				elementNode := elementCell.Find("a").FilterFunction(func(l int, a *goquery.Selection) bool {
+					return packOf(a) == ""
+				}).Last()
 				element := cleanText(elementNode.Text())
 
 				// Extract image URL from the img tag - use wikia standard URL format
@@ -164,6 +207,13 @@ func ScrapeElements() ([]ElementGroup, error) {
 
 				log.Printf("Element %q assigned tier %d, image: %s", element, currentTierNum, imgSrc)
 
+				// Pack elements are either marked in their cell or listed in a pack section
+				if pack := findPack(elementCell); pack != "" {
+					packMap[element] = pack
+				} else if currentPack != "" {
+					packMap[element] = currentPack
+				}
+
 				// Extract recipes from the second cell
 				recipesCell := cells.Eq(1)
 				hasRecipes := false
@@ -177,9 +227,12 @@ func ScrapeElements() ([]ElementGroup, error) {
 
 				// Process regular recipes found in list items
 				recipesCell.Find("ul li").Each(func(k int, li *goquery.Selection) {
-					// Extract ingredients from <a> tags
+					// Extract ingredients from <a> tags, skipping pack markers
 					var recipe []string
 					li.Find("a").Each(func(l int, a *goquery.Selection) {
+						if packOf(a) != "" {
+							return
+						}
 						ing := cleanText(a.Text())
 						if ing != "" {
 							recipe = append(recipe, ing)
@@ -191,6 +244,14 @@ func ScrapeElements() ([]ElementGroup, error) {
 						recipeMap[element] = append(recipeMap[element], recipe)
 						hasRecipes = true
 						log.Printf("Added recipe for %q: %v (tier: %d)", element, recipe, currentTierNum)
+
+						if pack := findPack(li); pack != "" {
+							if packRecipeMap[element] == nil {
+								packRecipeMap[element] = make(map[string][][]string)
+							}
+							packRecipeMap[element][pack] = append(packRecipeMap[element][pack], recipe)
+							log.Printf("Recipe %v for %q needs pack %s", recipe, element, pack)
+						}
 					}
 				})
 
@@ -198,8 +259,9 @@ func ScrapeElements() ([]ElementGroup, error) {
 				if !hasRecipes && strings.Contains(recipesCell.Text(), "unlock") {
 					// Special elements like Time
 					recipeMap[element] = [][]string{}
+					unlockMap[element] = parseUnlockCondition(recipesCell.Text())
 					hasRecipes = true
-					log.Printf("Added special element %q with unlock requirement", element)
+					log.Printf("Added special element %q with unlock requirement: %s", element, unlockMap[element].Text)
 				}
 
 				// Add element even if it has no recipes
@@ -216,19 +278,13 @@ func ScrapeElements() ([]ElementGroup, error) {
 
 	// Convert maps to Element structs and group by tier
 	for name, recipes := range recipeMap {
-		// Sort ingredients within each recipe
-		for _, recipe := range recipes {
-			sort.Strings(recipe)
+		// Pack recipes share their ingredient slices with recipes, so they
+		// are sorted along with them
+		sortRecipes(recipes)
+		for _, packRecipes := range packRecipeMap[name] {
+			sortRecipes(packRecipes)
 		}
 
-		// Sort recipes by first ingredient
-		sort.Slice(recipes, func(i, j int) bool {
-			if len(recipes[i]) == 0 || len(recipes[j]) == 0 {
-				return len(recipes[i]) < len(recipes[j])
-			}
-			return recipes[i][0] < recipes[j][0]
-		})
-
 		// Get tierNum and imageURL, default to -1 and empty string if not found
 		tierNum, exists := tierNumMap[name]
 		if !exists {
@@ -247,6 +303,10 @@ func ScrapeElements() ([]ElementGroup, error) {
 			Name:     name,
 			Recipes:  recipes,
 			ImageURL: imageURL,
+			Unlock:   unlockMap[name],
+			Pack:     packMap[name],
+
+			PackRecipes: packRecipeMap[name],
 		}
 
 		tierGroups[tierNum] = append(tierGroups[tierNum], element)
@@ -280,6 +340,8 @@ func ScrapeElements() ([]ElementGroup, error) {
 		return nil, fmt.Errorf("no elements found")
 	}
 
+	applyKnownUnlocks(result)
+
 	// Print tier statistics
 	for _, group := range result {
 		log.Printf("Tier %d: %d elements", group.TierNum, len(group.Elements))
@@ -293,6 +355,86 @@ func recipeMapContainsElement(recipeMap map[string][][]string, element string) b
 	return exists
 }
 
+// sortRecipes sorts the ingredients within each recipe, then the recipes by
+// first ingredient
+func sortRecipes(recipes [][]string) {
+	for _, recipe := range recipes {
+		sort.Strings(recipe)
+	}
+
+	sort.Slice(recipes, func(i, j int) bool {
+		if len(recipes[i]) == 0 || len(recipes[j]) == 0 {
+			return len(recipes[i]) < len(recipes[j])
+		}
+		return recipes[i][0] < recipes[j][0]
+	})
+}
+
+// packOf returns the expansion pack a heading, link or image refers to, or an
+// empty string for base-game content
+func packOf(s *goquery.Selection) string {
+	texts := []string{cleanText(s.Text())}
+	for _, attr := range []string{"title", "alt", "href"} {
+		if value, exists := s.Attr(attr); exists {
+			texts = append(texts, strings.ReplaceAll(value, "_", " "))
+		}
+	}
+
+	for _, text := range texts {
+		for _, pack := range expansionPacks {
+			if strings.Contains(strings.ToLower(text), strings.ToLower(pack)) {
+				return pack
+			}
+		}
+	}
+	return ""
+}
+
+// findPack returns the first expansion pack marked by a link or image inside s
+func findPack(s *goquery.Selection) string {
+	pack := ""
+	s.Find("a, img").EachWithBreak(func(i int, marker *goquery.Selection) bool {
+		pack = packOf(marker)
+		return pack == ""
+	})
+	return pack
+}
+
+// applyKnownUnlocks fills in the unlock condition of special elements listed
+// in knownUnlocks when the scraped one is missing or has no element count. It
+// returns the names of the elements it changed.
+func applyKnownUnlocks(groups []ElementGroup) []string {
+	var patched []string
+	for g := range groups {
+		for e := range groups[g].Elements {
+			element := &groups[g].Elements[e]
+			known, exists := knownUnlocks[element.Name]
+			if !exists || len(element.Recipes) > 0 {
+				continue
+			}
+			if element.Unlock != nil && element.Unlock.Discovered > 0 {
+				continue
+			}
+
+			condition := known
+			element.Unlock = &condition
+			patched = append(patched, element.Name)
+			log.Printf("Using known unlock requirement for %q: %s", element.Name, condition.Text)
+		}
+	}
+	return patched
+}
+
+// parseUnlockCondition captures the unlock text of a special element and the
+// number of elements it asks to discover, if any
+func parseUnlockCondition(text string) *UnlockCondition {
+	condition := &UnlockCondition{Text: cleanText(text)}
+	if match := discoveredPattern.FindStringSubmatch(condition.Text); match != nil {
+		condition.Discovered, _ = strconv.Atoi(match[1])
+	}
+	return condition
+}
+
 // cleanText normalizes text by removing extra whitespace, newlines, and tabs
 func cleanText(s string) string {
 	s = strings.TrimSpace(s)
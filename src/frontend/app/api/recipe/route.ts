@@ -1,17 +1,61 @@
 import { NextRequest, NextResponse } from 'next/server';
-import { exec } from 'child_process';
+import { execFile, spawn } from 'child_process';
 import { promisify } from 'util';
 import path from 'path';
 import { promises as fs } from 'fs';
 
-const execAsync = promisify(exec);
+const execFileAsync = promisify(execFile);
+
+// Searches longer than this are stopped by the Go program, which prints the
+// paths it found by then
+const SEARCH_TIMEOUT = "60s";
+
+// The Go program is built once per server and run directly. With `go run`
+// behind a shell, aborting a request only killed the shell and left the
+// search running.
+let backendBinary: Promise<string> | null = null;
+
+function buildBackend(backendPath: string): Promise<string> {
+  if (!backendBinary) {
+    const binary = path.join(backendPath, process.platform === "win32" ? "Algorithm.exe" : "Algorithm");
+    console.log(`Building Go backend: ${binary}`);
+    backendBinary = execFileAsync("go", ["build", "-o", binary, "."], { cwd: backendPath }).then(() => binary);
+
+    // Try again on the next request if the build failed
+    backendBinary.catch(() => { backendBinary = null; });
+  }
+  return backendBinary;
+}
+
+// Run the Go program with input on stdin. Aborting signal kills the program
+// itself, since nothing sits in between.
+function runBackend(binary: string, cwd: string, input: string, signal: AbortSignal): Promise<{ stdout: string; stderr: string }> {
+  return new Promise((resolve, reject) => {
+    const child = spawn(binary, [`-timeout=${SEARCH_TIMEOUT}`], { cwd, signal });
+    let stdout = "";
+    let stderr = "";
+    child.stdout.on("data", (chunk) => { stdout += chunk; });
+    child.stderr.on("data", (chunk) => { stderr += chunk; });
+    child.on("error", reject);
+    child.on("close", (code) => {
+      if (code === 0) {
+        resolve({ stdout, stderr });
+      } else {
+        reject(new Error(`Go backend exited with code ${code}: ${stderr}`));
+      }
+    });
+    child.stdin.end(input);
+  });
+}
 
 export async function POST(req: NextRequest) {
   try {
     const body = await req.json();
     const { target, algorithm, mode, maxPaths = 3 } = body;
 
-    if (!target || !algorithm) {
+    // Multiple paths are ranked by cost whatever the algorithm, so only a
+    // single path search needs one
+    if (!target || (mode === "single" && !algorithm)) {
       return NextResponse.json({ error: 'Missing required parameters' }, { status: 400 });
     }
 
@@ -42,47 +86,21 @@ export async function POST(req: NextRequest) {
       }
     }
     
-    // Map algorithm names to numeric codes expected by the Go program
-    let algoArg = "1"; // Default to BFS
-    if (algorithm.toLowerCase() === "dfs") {
-      algoArg = "2";
-    } else if (algorithm.toLowerCase() === "bidirectional") {
-      algoArg = "3";
-    }
+    // The Go program looks algorithms up by name in its registry
+    const algoArg = mode === "single" ? algorithm.toLowerCase() : "ranked";
     
     // Map mode to numeric code
     let modeArg = mode === "single" ? "1" : "2";
     
-    // Create a temporary input file to simulate user input for the Go prograam
-    const inputFilePath = path.join(backendPath, "temp_input.txt");
-    const inputContent = `${target}\n${modeArg}\n${algoArg}\n${mode === "multiple" ? maxPaths + "\n" : ""}`;
-    
-    await fs.writeFile(inputFilePath, inputContent);
-    
-    // Prepare command to run the Go program, using < to pipe the input file
-    // On Windows we need to use a different approach
-    const isWindows = process.platform === "win32";
+    // Answers to the Go program's prompts: the algorithm for a single path,
+    // the number of paths for multiple ones
+    const inputContent = `${target}\n${modeArg}\n${mode === "single" ? algoArg : maxPaths}\n`;
     
-    let command;
-    if (isWindows) {
-      // Using type command in Windows to pipe file content
-      command = `cd "${backendPath}" && go run main.go bfs.go dfs.go bid.go < temp_input.txt`;
-    } else {
-      // Unix-style input redirection
-      command = `cd "${backendPath}" && go run main.go bfs.go dfs.go bid.go < "${inputFilePath}"`;
-    }
-    
-    console.log(`Executing: ${command}`);
-    
-    // Run the Go program
-    const { stdout, stderr } = await execAsync(command);
+    const binary = await buildBackend(backendPath);
+    console.log(`Executing: ${binary} -timeout=${SEARCH_TIMEOUT}`);
     
-    // Clean up the temporary input file
-    try {
-      await fs.unlink(inputFilePath);
-    } catch (err) {
-      console.warn("Could not delete temporary input file:", err);
-    }
+    // Run the Go program, stopping it if the client goes away
+    const { stdout, stderr } = await runBackend(binary, backendPath, inputContent, req.signal);
     
     if (stderr && !stderr.includes("Loaded")) {
       console.error('Error from Go backend:', stderr);
@@ -132,6 +150,26 @@ export async function POST(req: NextRequest) {
       }
     }
     
+    // Searches that find nothing print a one line JSON diagnostic explaining why
+    const diagnosticMatch = stdout.match(/No path diagnostic: (.+)/);
+    if (recipePath.length === 0 && diagnosticMatch) {
+      const messageMatch = stdout.match(/Error: (no path found.*)/);
+      return NextResponse.json({
+        error: 'No path found',
+        message: messageMatch ? messageMatch[1] : 'no path found',
+        diagnostic: JSON.parse(diagnosticMatch[1])
+      }, { status: 404 });
+    }
+
+    // Searches stopped by the timeout still return the paths found before it
+    const timedOutMatch = stdout.match(/search timed out after .+/);
+    if (recipePath.length === 0 && timedOutMatch) {
+      return NextResponse.json({
+        error: 'Search timed out',
+        message: timedOutMatch[0]
+      }, { status: 504 });
+    }
+
     // If we still couldn't parse the path, return the raw output for debugging
     if (recipePath.length === 0 && stdout.trim() !== '') {
       return NextResponse.json({
@@ -149,8 +187,10 @@ export async function POST(req: NextRequest) {
 
     return NextResponse.json({
         path: recipePath,
+        algorithm: algoArg,
         visitedNodes,
         executionTime,
+        timedOut: timedOutMatch !== null,
         treeStructure,
         // Include the detailed path information
         detailedPath: treeStructure.detailedPath || [],
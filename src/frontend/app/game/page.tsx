@@ -168,6 +168,20 @@ export default function GamePage() {
         }),
       });
       
+      // The backend explains why no path exists
+      if (response.status === 404) {
+        const failure = await response.json();
+        alert(`Recipe tidak ditemukan: ${failure.message}`);
+        return;
+      }
+
+      // The search ran out of time before finding a path
+      if (response.status === 504) {
+        const failure = await response.json();
+        alert(`Pencarian melebihi batas waktu: ${failure.message}`);
+        return;
+      }
+
       if (!response.ok) {
         throw new Error(`API request failed with status ${response.status}`);
       }
@@ -398,7 +412,7 @@ export default function GamePage() {
       <h2 className="text-2xl font-semibold mb-4">Pilih Algoritma</h2>
 
       <div className="flex flex-wrap gap-4 mb-6">
-        {["BFS", "DFS", "BIDIRECTIONAL"].map((algo) => (
+        {["BFS", "DFS", "BIDIRECTIONAL", "ASTAR"].map((algo) => (
           <button
             key={algo}
             onClick={() => setAlgorithm(algo)}
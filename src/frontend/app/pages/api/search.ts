@@ -46,14 +46,7 @@ export default async function handler(
       '..', '..', 'backend', 'src', 'Algorithm'
     );
 
-    const goFiles = [
-      'main.go',
-      'bfs.go',
-      'dfs.go',
-      'bid.go'
-    ].map(file => path.join(goDir, file)).join(' ');
-
-    const command = `go run ${goFiles} -target="${target}" -algo="${algo}" -mode="${mode}" -max=${maxNum}`;
+    const command = `go run . -target="${target}" -algo="${algo}" -mode="${mode}" -max=${maxNum}`;
 
     const { stdout, stderr } = await execPromise(command, { cwd: goDir });
 